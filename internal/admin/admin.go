@@ -0,0 +1,156 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package admin provides an HTTP API, meant to be served locally only, listing the active connections of the
+// forwarder along with their dataplane details
+package admin
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/kernel"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/memif"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/vxlan"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/conntrack"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/httpserve"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/ifnames"
+)
+
+// Path - HTTP path the active connections are listed on.  A single connection is served on Path + "/" + its id,
+// either the forwarder's or its client's.
+const Path = "/connections"
+
+// Tracker - keeps track of the active connections, as conntrack.Server does
+type Tracker interface {
+	Entries() []*conntrack.Entry
+}
+
+// Connection - an active connection
+type Connection struct {
+	// ID - id of the forwarder's path segment of the connection, which the names of its interfaces derive from
+	ID string `json:"id"`
+	// ClientID - id of the connection as known to the client of the forwarder
+	ClientID         string    `json:"clientId"`
+	NetworkService   string    `json:"networkService"`
+	ClientSpiffeID   string    `json:"clientSpiffeId,omitempty"`
+	EndpointSpiffeID string    `json:"endpointSpiffeId,omitempty"`
+	Local            *Side     `json:"local"`
+	Remote           *Side     `json:"remote,omitempty"`
+	Created          time.Time `json:"created"`
+	Refreshed        time.Time `json:"refreshed"`
+}
+
+// Side - the dataplane details of the incoming (local) or outgoing (remote) side of a connection
+type Side struct {
+	Mechanism       string `json:"mechanism"`
+	VppInterface    string `json:"vppInterface,omitempty"`
+	VxlanVNI        uint32 `json:"vxlanVni,omitempty"`
+	VxlanPeerIP     string `json:"vxlanPeerIp,omitempty"`
+	KernelInterface string `json:"kernelInterface,omitempty"`
+	NetNS           string `json:"netns,omitempty"`
+	MemifSocket     string `json:"memifSocket,omitempty"`
+}
+
+// NewHandler - returns an http.Handler serving the connections tracked by tracker as JSON
+func NewHandler(tracker Tracker) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(Path, func(w http.ResponseWriter, r *http.Request) {
+		httpserve.WriteJSON(w, http.StatusOK, Connections(tracker))
+	})
+	mux.HandleFunc(Path+"/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, Path+"/")
+		for _, conn := range Connections(tracker) {
+			if conn.ID == id || conn.ClientID == id {
+				httpserve.WriteJSON(w, http.StatusOK, conn)
+				return
+			}
+		}
+		http.Error(w, "no such connection: "+id, http.StatusNotFound)
+	})
+	return mux
+}
+
+// Connections - returns the details of the connections tracked by tracker, ordered by creation time
+func Connections(tracker Tracker) []*Connection {
+	entries := tracker.Entries()
+	conns := make([]*Connection, 0, len(entries))
+	for _, entry := range entries {
+		own := conntrack.ForwarderView(entry.Connection)
+		if own == nil {
+			continue
+		}
+		conn := &Connection{
+			ID:             own.GetId(),
+			ClientID:       entry.Connection.GetId(),
+			NetworkService: own.GetNetworkService(),
+			ClientSpiffeID: spiffeID(own.GetPath().GetPathSegments()[0]),
+			Local:          side(own, ifnames.Server(own.GetMechanism(), own.GetId()), true),
+			Created:        entry.Created,
+			Refreshed:      entry.Refreshed,
+		}
+		if outgoing := entry.Outgoing; outgoing != nil {
+			if segments := outgoing.GetPath().GetPathSegments(); len(segments) > 0 {
+				conn.EndpointSpiffeID = spiffeID(segments[len(segments)-1])
+			}
+			conn.Remote = side(outgoing, ifnames.Client(outgoing.GetMechanism(), outgoing.GetId()), false)
+		}
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+// side - returns the details of the side of conn whose VPP interface is vppInterface.  The forwarder is the server
+// of the incoming side, and the client of the outgoing one.
+func side(conn *networkservice.Connection, vppInterface string, incoming bool) *Side {
+	mechanism := conn.GetMechanism()
+	s := &Side{
+		Mechanism:    mechanism.GetType(),
+		VppInterface: vppInterface,
+	}
+	switch mechanism.GetType() {
+	case vxlan.MECHANISM:
+		m := vxlan.ToMechanism(mechanism)
+		s.VxlanVNI = m.VNI()
+		peer := m.DstIP()
+		if incoming {
+			peer = m.SrcIP()
+		}
+		if peer != nil {
+			s.VxlanPeerIP = peer.String()
+		}
+	case kernel.MECHANISM:
+		m := kernel.ToMechanism(mechanism)
+		s.KernelInterface = m.GetInterfaceName(conn)
+		s.NetNS = m.GetNetNSURL()
+	case memif.MECHANISM:
+		s.MemifSocket = memif.ToMechanism(mechanism).GetSocketFilename()
+	}
+	return s
+}
+
+// spiffeID - returns the SPIFFE ID of the workload that added segment to the path, which is the subject of its token
+func spiffeID(segment *networkservice.PathSegment) string {
+	claims := &jwt.StandardClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(segment.GetToken(), claims); err != nil {
+		return ""
+	}
+	return claims.Subject
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admin_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/kernel"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/vxlan"
+	"github.com/stretchr/testify/require"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/admin"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/conntrack"
+)
+
+type testTracker []*conntrack.Entry
+
+func (t testTracker) Entries() []*conntrack.Entry {
+	return t
+}
+
+func token(t *testing.T, spiffeID string) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.StandardClaims{Subject: spiffeID}).SignedString([]byte("key"))
+	require.NoError(t, err)
+	return token
+}
+
+func TestHandler(t *testing.T) {
+	segments := []*networkservice.PathSegment{
+		{Name: "nsc", Id: "nsc-id", Token: token(t, "spiffe://example.org/nsc")},
+		{Name: "forwarder", Id: "fwd-id", Token: token(t, "spiffe://example.org/forwarder")},
+		{Name: "nse", Id: "nse-id", Token: token(t, "spiffe://example.org/nse")},
+	}
+	localMechanism := kernel.New("file:///proc/42/ns/net")
+	kernel.ToMechanism(localMechanism).SetInterfaceName("nsm0")
+	remoteMechanism := &networkservice.Mechanism{Type: vxlan.MECHANISM, Parameters: map[string]string{}}
+	vxlan.ToMechanism(remoteMechanism).SetSrcIP(net.ParseIP("10.0.0.1")).SetDstIP(net.ParseIP("10.0.0.2")).SetVNI(42)
+	created := time.Now().Add(-time.Minute).UTC().Round(time.Second)
+	tracker := testTracker{{
+		Connection: &networkservice.Connection{
+			Id:             "nsc-id",
+			NetworkService: "ns",
+			Mechanism:      localMechanism,
+			Path:           &networkservice.Path{Index: 0, PathSegments: segments[:2]},
+		},
+		Outgoing: &networkservice.Connection{
+			Id:             "fwd-id",
+			NetworkService: "ns",
+			Mechanism:      remoteMechanism,
+			Path:           &networkservice.Path{Index: 1, PathSegments: segments},
+		},
+		Created:   created,
+		Refreshed: created.Add(time.Second),
+	}}
+	handler := admin.NewHandler(tracker)
+
+	for _, path := range []string{admin.Path + "/fwd-id", admin.Path + "/nsc-id"} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		conn := &admin.Connection{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), conn))
+		require.Equal(t, &admin.Connection{
+			ID:               "fwd-id",
+			ClientID:         "nsc-id",
+			NetworkService:   "ns",
+			ClientSpiffeID:   "spiffe://example.org/nsc",
+			EndpointSpiffeID: "spiffe://example.org/nse",
+			Local: &admin.Side{
+				Mechanism:       kernel.MECHANISM,
+				VppInterface:    "server-fwd-id",
+				KernelInterface: "nsm0",
+				NetNS:           "file:///proc/42/ns/net",
+			},
+			Remote: &admin.Side{
+				Mechanism:    vxlan.MECHANISM,
+				VppInterface: "fwd-id",
+				VxlanVNI:     42,
+				VxlanPeerIP:  "10.0.0.2",
+			},
+			Created:   created,
+			Refreshed: created.Add(time.Second),
+		}, conn)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, admin.Path, nil))
+	var conns []*admin.Connection
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &conns))
+	require.Len(t, conns, 1)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, admin.Path+"/unknown", nil))
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import "github.com/networkservicemesh/api/pkg/api/networkservice"
+
+// Option - option for use with NewServer(...)
+type Option func(s *Server)
+
+// WithMaxInflightRequests - sets the maximum number of Requests handled at once, 0 for no limit
+func WithMaxInflightRequests(maxInflightRequests int) Option {
+	return func(s *Server) {
+		s.maxInflightRequests = maxInflightRequests
+	}
+}
+
+// WithMaxConnections - sets the maximum number of active connections, 0 for no limit.  count returns the number of
+// active connections and isActive whether a connection is one of them, whose refreshes are always accepted.  Both
+// must reflect a Request as soon as the rest of the chain has handled it.
+func WithMaxConnections(maxConnections int, count func() int, isActive func(conn *networkservice.Connection) bool) Option {
+	return func(s *Server) {
+		s.maxConnections = maxConnections
+		s.count = count
+		s.isActive = isActive
+	}
+}
+
+// WithRejectFunc - sets a function called with the reason, ReasonInflightRequests or ReasonConnections, of each
+// Request rejected
+func WithRejectFunc(rejectFunc func(reason string)) Option {
+	return func(s *Server) {
+		s.rejectFunc = rejectFunc
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package admission provides a NetworkServiceServer chain element bounding the number of Requests handled at once
+// and the number of active connections, so that a burst of Requests does not overload the forwarder or vppagent
+package admission
+
+import (
+	"context"
+	"sync"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/networkservicemesh/sdk/pkg/networkservice/core/next"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/conntrack"
+)
+
+const (
+	// ReasonInflightRequests - reason for rejecting a Request when the maximum number of Requests is being handled
+	ReasonInflightRequests = "inflight_requests"
+	// ReasonConnections - reason for rejecting a Request for a new connection when the maximum number of connections
+	// is active
+	ReasonConnections = "connections"
+)
+
+// Server - NetworkServiceServer chain element rejecting the Requests in excess of its limits with
+// codes.ResourceExhausted, for the client to retry with another forwarder
+type Server struct {
+	maxInflightRequests int
+	maxConnections      int
+	isActive            func(conn *networkservice.Connection) bool
+	count               func() int
+	rejectFunc          func(reason string)
+
+	// inflight - holds a token for each Request being handled, if maxInflightRequests is set
+	inflight chan struct{}
+
+	mu sync.Mutex
+	// pending - number of Requests for new connections being handled, which are not counted by count until they
+	// have succeeded
+	pending int
+}
+
+// NewServer - returns a new Server, which has no limits unless given some by options
+func NewServer(options ...Option) *Server {
+	s := &Server{
+		isActive:   func(*networkservice.Connection) bool { return false },
+		count:      func() int { return 0 },
+		rejectFunc: func(string) {},
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	if s.maxInflightRequests > 0 {
+		s.inflight = make(chan struct{}, s.maxInflightRequests)
+	}
+	return s
+}
+
+// Request - rejects the request with codes.ResourceExhausted if the maximum number of Requests is already being
+// handled, or if it is for a new connection and the maximum number of connections is active, counting those being
+// Requested.  Replays and restores of tracked connections, which were admitted before, are always let through.
+func (s *Server) Request(ctx context.Context, request *networkservice.NetworkServiceRequest) (*networkservice.Connection, error) {
+	if conntrack.IsReplay(ctx) {
+		return next.Server(ctx).Request(ctx, request)
+	}
+	if s.inflight != nil {
+		select {
+		case s.inflight <- struct{}{}:
+			defer func() { <-s.inflight }()
+		default:
+			s.rejectFunc(ReasonInflightRequests)
+			return nil, status.Errorf(codes.ResourceExhausted, "forwarder is already handling %d Requests", s.maxInflightRequests)
+		}
+	}
+	if s.maxConnections > 0 && !s.isActive(request.GetConnection()) {
+		if !s.reserve() {
+			s.rejectFunc(ReasonConnections)
+			return nil, status.Errorf(codes.ResourceExhausted, "forwarder already has %d connections", s.maxConnections)
+		}
+		defer s.release()
+	}
+	return next.Server(ctx).Request(ctx, request)
+}
+
+// Close - always passes the Close on, as it frees resources
+func (s *Server) Close(ctx context.Context, conn *networkservice.Connection) (*empty.Empty, error) {
+	return next.Server(ctx).Close(ctx, conn)
+}
+
+// reserve - reserves room for a new connection, returning false if there is none left
+func (s *Server) reserve() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.count()+s.pending >= s.maxConnections {
+		return false
+	}
+	s.pending++
+	return true
+}
+
+func (s *Server) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending--
+}
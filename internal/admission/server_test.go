@@ -0,0 +1,181 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/networkservicemesh/sdk/pkg/networkservice/core/chain"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/admission"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/conntrack"
+)
+
+// trackingServer - keeps track of the connections Requested, each Request blocking until unblock is closed
+type trackingServer struct {
+	started chan struct{}
+	unblock chan struct{}
+
+	mu    sync.Mutex
+	conns map[string]bool
+}
+
+func newTrackingServer() *trackingServer {
+	unblock := make(chan struct{})
+	close(unblock)
+	return &trackingServer{
+		started: make(chan struct{}, 10),
+		unblock: unblock,
+		conns:   make(map[string]bool),
+	}
+}
+
+func (s *trackingServer) Request(_ context.Context, request *networkservice.NetworkServiceRequest) (*networkservice.Connection, error) {
+	s.started <- struct{}{}
+	<-s.unblock
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conns[request.GetConnection().GetId()] = true
+	return request.GetConnection(), nil
+}
+
+func (s *trackingServer) Close(_ context.Context, conn *networkservice.Connection) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn.GetId())
+	return &empty.Empty{}, nil
+}
+
+func (s *trackingServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+func (s *trackingServer) isActive(conn *networkservice.Connection) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns[conn.GetId()]
+}
+
+func request(server networkservice.NetworkServiceServer, id string) error {
+	_, err := server.Request(context.Background(), &networkservice.NetworkServiceRequest{
+		Connection: &networkservice.Connection{Id: id},
+	})
+	return err
+}
+
+func TestServer_MaxConnections(t *testing.T) {
+	tracking := newTrackingServer()
+	var rejected []string
+	server := chain.NewNetworkServiceServer(
+		admission.NewServer(
+			admission.WithMaxConnections(2, tracking.count, tracking.isActive),
+			admission.WithRejectFunc(func(reason string) { rejected = append(rejected, reason) }),
+		),
+		tracking,
+	)
+
+	require.NoError(t, request(server, "1"))
+	require.NoError(t, request(server, "2"))
+	err := request(server, "3")
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{admission.ReasonConnections}, rejected)
+
+	// Refreshes of active connections are accepted
+	require.NoError(t, request(server, "1"))
+
+	_, err = server.Close(context.Background(), &networkservice.Connection{Id: "1"})
+	require.NoError(t, err)
+	require.NoError(t, request(server, "3"))
+}
+
+func TestServer_MaxConnectionsPending(t *testing.T) {
+	tracking := newTrackingServer()
+	tracking.unblock = make(chan struct{})
+	server := chain.NewNetworkServiceServer(
+		admission.NewServer(admission.WithMaxConnections(1, tracking.count, tracking.isActive)),
+		tracking,
+	)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- request(server, "1") }()
+	<-tracking.started
+	// The connection being Requested counts although it is not active yet
+	require.Equal(t, codes.ResourceExhausted, status.Code(request(server, "2")))
+	close(tracking.unblock)
+	require.NoError(t, <-errCh)
+	require.Equal(t, 1, tracking.count())
+}
+
+func TestServer_MaxInflightRequests(t *testing.T) {
+	tracking := newTrackingServer()
+	tracking.unblock = make(chan struct{})
+	var rejected []string
+	server := chain.NewNetworkServiceServer(
+		admission.NewServer(
+			admission.WithMaxInflightRequests(2),
+			admission.WithRejectFunc(func(reason string) { rejected = append(rejected, reason) }),
+		),
+		tracking,
+	)
+
+	errCh := make(chan error, 2)
+	go func() { errCh <- request(server, "1") }()
+	go func() { errCh <- request(server, "2") }()
+	<-tracking.started
+	<-tracking.started
+	require.Equal(t, codes.ResourceExhausted, status.Code(request(server, "3")))
+	require.Equal(t, []string{admission.ReasonInflightRequests}, rejected)
+
+	close(tracking.unblock)
+	require.NoError(t, <-errCh)
+	require.NoError(t, <-errCh)
+	require.NoError(t, request(server, "3"))
+}
+
+func TestServer_NoLimits(t *testing.T) {
+	tracking := newTrackingServer()
+	server := chain.NewNetworkServiceServer(admission.NewServer(), tracking)
+	for _, id := range []string{"1", "2", "3"} {
+		require.NoError(t, request(server, id))
+	}
+}
+
+func TestServer_Replay(t *testing.T) {
+	tracker := conntrack.NewServer()
+	tracking := newTrackingServer()
+	inactive := func(*networkservice.Connection) bool { return false }
+	server := chain.NewNetworkServiceServer(
+		tracker,
+		admission.NewServer(admission.WithMaxConnections(1, tracking.count, inactive)),
+		tracking,
+	)
+
+	require.NoError(t, request(server, "1"))
+	require.Equal(t, codes.ResourceExhausted, status.Code(request(server, "1")))
+	// The connection was admitted when first Requested, so replaying it after a vppagent restart is not limited
+	require.NoError(t, tracker.Replay(context.Background(), server))
+}
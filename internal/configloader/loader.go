@@ -0,0 +1,448 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package configloader populates an envconfig specification from, in increasing order of precedence, its defaults, an
+// optional YAML or JSON file and environment variables, keeping track of where each value came from.
+//
+// Keys in the file are the lowerCamelCase names of the struct fields (or the name given in a `yaml` tag), and
+// nested structs are nested sections of the file.  Values are parsed the same way as envconfig parses environment
+// variables, except that lists and maps may also be given as YAML sequences and mappings.
+package configloader
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"github.com/kelseyhightower/envconfig"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// SourceDefault - the value is the field's default
+	SourceDefault = "default"
+	// SourceUnset - the value is the field's zero value
+	SourceUnset = "unset"
+)
+
+var (
+	gatherRegexp  = regexp.MustCompile("([^A-Z]+|[A-Z]+[^A-Z]+|[A-Z]+)")
+	acronymRegexp = regexp.MustCompile("([A-Z]+)([A-Z][^A-Z]+)")
+)
+
+// Field - a leaf field of the specification and where its value came from
+type Field struct {
+	// Key - dot separated path of the field in the config file
+	Key string
+	// Env - name of the environment variable for the field
+	Env string
+	// Source - where the value came from: SourceDefault, SourceUnset, "env <variable>" or "file <filename>"
+	Source string
+
+	value reflect.Value
+}
+
+// Value - returns the value of the field formatted for display
+func (f *Field) Value() string {
+	return format(f.value)
+}
+
+// format - formats v with its String method if it has one, and slices with the String methods of their items
+func format(v reflect.Value) string {
+	if v.CanAddr() {
+		if stringer, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			return stringer.String()
+		}
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = format(v.Index(i))
+		}
+		return "[" + strings.Join(items, " ") + "]"
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// Load - populates spec, which must be a pointer to a struct, from defaults, filename (if not empty) and environment
+//        variables with prefix, the latter taking precedence.  Keys in the file that do not correspond to a field of
+//        spec are an error.
+func Load(prefix string, spec interface{}, filename string) ([]*Field, error) {
+	if err := envconfig.Process(prefix, spec); err != nil {
+		return nil, errors.Wrap(err, "error processing config from env")
+	}
+	fields := gatherFields(strings.ToUpper(prefix), "", reflect.ValueOf(spec).Elem())
+	if filename == "" {
+		return fields, nil
+	}
+
+	contents, err := ioutil.ReadFile(filename) // #nosec
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading config file %s", filename)
+	}
+	values := make(map[string]interface{})
+	if err = yaml.Unmarshal(contents, &values); err != nil {
+		return nil, errors.Wrapf(err, "error parsing config file %s", filename)
+	}
+	fieldsByKey := make(map[string]*Field, len(fields))
+	for _, field := range fields {
+		fieldsByKey[field.Key] = field
+	}
+	if err = applyFile(fieldsByKey, "", values, "file "+filename); err != nil {
+		return nil, errors.Wrapf(err, "error in config file %s", filename)
+	}
+	return fields, nil
+}
+
+// Print - writes fields to w as a table of keys, environment variables, values and their sources
+func Print(w io.Writer, fields []*Field) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "KEY\tENV\tVALUE\tSOURCE"); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, field := range fields {
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", field.Key, field.Env, field.Value(), field.Source); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return errors.WithStack(tw.Flush())
+}
+
+// gatherFields - walks v the same way envconfig does, returning its leaf fields with the environment variables
+// envconfig will have used for them
+func gatherFields(envPrefix, keyPrefix string, v reflect.Value) []*Field {
+	var fields []*Field
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		ftype := v.Type().Field(i)
+		if !f.CanSet() || isTrue(ftype.Tag.Get("ignored")) {
+			continue
+		}
+		for f.Kind() == reflect.Ptr && !f.IsNil() {
+			f = f.Elem()
+		}
+		field := &Field{
+			Key:   fileKey(keyPrefix, ftype),
+			Env:   envKey(envPrefix, ftype),
+			value: f,
+		}
+		if f.Kind() == reflect.Struct && !isLeaf(f) {
+			innerEnvPrefix, innerKeyPrefix := envPrefix, keyPrefix
+			if !ftype.Anonymous {
+				innerEnvPrefix, innerKeyPrefix = field.Env, field.Key
+			}
+			fields = append(fields, gatherFields(innerEnvPrefix, innerKeyPrefix, f)...)
+			continue
+		}
+		switch _, ok := os.LookupEnv(field.Env); {
+		case ok:
+			field.Source = "env " + field.Env
+		case ftype.Tag.Get("default") != "":
+			field.Source = SourceDefault
+		default:
+			field.Source = SourceUnset
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// applyFile - sets each of values not overridden by an environment variable on the corresponding field
+func applyFile(fieldsByKey map[string]*Field, keyPrefix string, values map[string]interface{}, source string) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := values[name]
+		key := name
+		if keyPrefix != "" {
+			key = keyPrefix + "." + name
+		}
+		if section, ok := toStringMap(value); ok && isSection(fieldsByKey, key) {
+			if err := applyFile(fieldsByKey, key, section, source); err != nil {
+				return err
+			}
+			continue
+		}
+		field, ok := fieldsByKey[key]
+		if !ok {
+			return errors.Errorf("unknown field %q", key)
+		}
+		if strings.HasPrefix(field.Source, "env ") {
+			continue
+		}
+		if err := setValue(field.value, value); err != nil {
+			return errors.Wrapf(err, "invalid value for %q", key)
+		}
+		field.Source = source
+	}
+	return nil
+}
+
+// isSection - returns true if key is the key of a nested struct rather than of a leaf field
+func isSection(fieldsByKey map[string]*Field, key string) bool {
+	if _, ok := fieldsByKey[key]; ok {
+		return false
+	}
+	for k := range fieldsByKey {
+		if strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// setValue - sets the leaf field v from a value decoded from YAML
+func setValue(v reflect.Value, value interface{}) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		if items, ok := value.([]interface{}); ok && v.Type().Elem().Kind() != reflect.Uint8 {
+			sl := reflect.MakeSlice(v.Type(), len(items), len(items))
+			for i, item := range items {
+				if err := setValue(sl.Index(i), item); err != nil {
+					return err
+				}
+			}
+			v.Set(sl)
+			return nil
+		}
+	case reflect.Map:
+		if items, ok := toStringMap(value); ok {
+			mp := reflect.MakeMap(v.Type())
+			for key, item := range items {
+				k := reflect.New(v.Type().Key()).Elem()
+				if err := parseValue(k, key); err != nil {
+					return err
+				}
+				e := reflect.New(v.Type().Elem()).Elem()
+				if err := setValue(e, item); err != nil {
+					return err
+				}
+				mp.SetMapIndex(k, e)
+			}
+			v.Set(mp)
+			return nil
+		}
+	}
+	if value == nil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	if _, ok := value.(map[interface{}]interface{}); ok {
+		return errors.Errorf("expected a value but got a mapping")
+	}
+	if _, ok := value.([]interface{}); ok {
+		return errors.Errorf("expected a value but got a sequence")
+	}
+	return parseValue(v, fmt.Sprint(value))
+}
+
+// parseValue - parses value into v with the same semantics as envconfig uses for environment variables
+func parseValue(v reflect.Value, value string) error {
+	if v.CanAddr() {
+		switch u := v.Addr().Interface().(type) {
+		case envconfig.Decoder:
+			return u.Decode(value)
+		case envconfig.Setter:
+			return u.Set(value)
+		case encoding.TextUnmarshaler:
+			return u.UnmarshalText([]byte(value))
+		case encoding.BinaryUnmarshaler:
+			return u.UnmarshalBinary([]byte(value))
+		}
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return parseValue(v.Elem(), value)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		i, err := strconv.ParseInt(value, 0, v.Type().Bits())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 0, v.Type().Bits())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		v.SetUint(u)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		v.SetBool(b)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		return parseSlice(v, value)
+	case reflect.Map:
+		return parseMap(v, value)
+	default:
+		return errors.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func parseSlice(v reflect.Value, value string) error {
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		v.SetBytes([]byte(value))
+		return nil
+	}
+	sl := reflect.MakeSlice(v.Type(), 0, 0)
+	if strings.TrimSpace(value) != "" {
+		items := strings.Split(value, ",")
+		sl = reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := parseValue(sl.Index(i), item); err != nil {
+				return err
+			}
+		}
+	}
+	v.Set(sl)
+	return nil
+}
+
+func parseMap(v reflect.Value, value string) error {
+	mp := reflect.MakeMap(v.Type())
+	if strings.TrimSpace(value) != "" {
+		for _, pair := range strings.Split(value, ",") {
+			kv := strings.Split(pair, ":")
+			if len(kv) != 2 {
+				return errors.Errorf("invalid map item: %q", pair)
+			}
+			k := reflect.New(v.Type().Key()).Elem()
+			if err := parseValue(k, kv[0]); err != nil {
+				return err
+			}
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := parseValue(e, kv[1]); err != nil {
+				return err
+			}
+			mp.SetMapIndex(k, e)
+		}
+	}
+	v.Set(mp)
+	return nil
+}
+
+// toStringMap - converts a mapping decoded from YAML into a map[string]interface{}
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		rv := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			rv[fmt.Sprint(k)] = v
+		}
+		return rv, true
+	}
+	return nil, false
+}
+
+// isLeaf - returns true if the struct v is parsed from a single value rather than being a nested section
+func isLeaf(v reflect.Value) bool {
+	if !v.CanAddr() {
+		return false
+	}
+	switch v.Addr().Interface().(type) {
+	case envconfig.Decoder, envconfig.Setter, encoding.TextUnmarshaler, encoding.BinaryUnmarshaler:
+		return true
+	}
+	return false
+}
+
+// envKey - returns the environment variable envconfig uses for the field
+func envKey(prefix string, ftype reflect.StructField) string {
+	key := ftype.Name
+	if isTrue(ftype.Tag.Get("split_words")) {
+		var words []string
+		for _, match := range gatherRegexp.FindAllStringSubmatch(ftype.Name, -1) {
+			if m := acronymRegexp.FindStringSubmatch(match[0]); len(m) == 3 {
+				words = append(words, m[1], m[2])
+			} else {
+				words = append(words, match[0])
+			}
+		}
+		if len(words) > 0 {
+			key = strings.Join(words, "_")
+		}
+	}
+	if alt := ftype.Tag.Get("envconfig"); alt != "" {
+		key = alt
+	}
+	if prefix != "" {
+		key = prefix + "_" + key
+	}
+	return strings.ToUpper(key)
+}
+
+// fileKey - returns the key of the field in the config file: the name from its `yaml` tag if it has one, otherwise
+// its name in lowerCamelCase ("TunnelIP" -> "tunnelIP", "NSName" -> "nsName", "URL" -> "url")
+func fileKey(prefix string, ftype reflect.StructField) string {
+	key := strings.Split(ftype.Tag.Get("yaml"), ",")[0]
+	if key == "" {
+		runes := []rune(ftype.Name)
+		for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+			if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				break
+			}
+			runes[i] = unicode.ToLower(runes[i])
+		}
+		key = string(runes)
+	}
+	if prefix != "" {
+		key = prefix + "." + key
+	}
+	return key
+}
+
+func isTrue(s string) bool {
+	b, _ := strconv.ParseBool(s)
+	return b
+}
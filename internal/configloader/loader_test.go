@@ -0,0 +1,135 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configloader_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/configloader"
+)
+
+type section struct {
+	Enabled bool              `default:"false" desc:"whether it is enabled"`
+	Labels  map[string]string `desc:"labels"`
+}
+
+type testConfig struct {
+	Name             string        `default:"forwarder" desc:"Name of Endpoint"`
+	NSName           string        `default:"xconnectns" desc:"Name of Network Service"`
+	TunnelIP         net.IP        `desc:"IP to use for tunnels" split_words:"true"`
+	ConnectTo        url.URL       `default:"unix:///connect.to.socket" desc:"url to connect to" split_words:"true"`
+	MaxTokenLifetime time.Duration `default:"24h" desc:"maximum lifetime of tokens" split_words:"true"`
+	Peers            []string      `desc:"peers"`
+	Mirrors          []url.URL     `default:"tcp://10.0.0.1:5000,tcp://10.0.0.2:5000" desc:"mirrors"`
+	Section          section
+}
+
+func writeFile(t *testing.T, contents string) string {
+	dir, err := ioutil.TempDir("", t.Name())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	filename := filepath.Join(dir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(contents), 0600))
+	return filename
+}
+
+func sources(fields []*configloader.Field) map[string]string {
+	rv := make(map[string]string)
+	for _, field := range fields {
+		rv[field.Key] = field.Source
+	}
+	return rv
+}
+
+func TestLoad_Layering(t *testing.T) {
+	filename := writeFile(t, `
+name: from-file
+nsName: ns-from-file
+tunnelIP: 10.0.0.1
+connectTo: tcp://127.0.0.1:5000
+peers:
+  - a
+  - b
+section:
+  enabled: true
+  labels:
+    node: node1
+`)
+	require.NoError(t, os.Setenv("TEST_NAME", "from-env"))
+	defer func() { _ = os.Unsetenv("TEST_NAME") }()
+
+	cfg := &testConfig{}
+	fields, err := configloader.Load("test", cfg, filename)
+	require.NoError(t, err)
+
+	require.Equal(t, "from-env", cfg.Name)
+	require.Equal(t, "ns-from-file", cfg.NSName)
+	require.True(t, cfg.TunnelIP.Equal(net.ParseIP("10.0.0.1")))
+	require.Equal(t, "tcp://127.0.0.1:5000", cfg.ConnectTo.String())
+	require.Equal(t, 24*time.Hour, cfg.MaxTokenLifetime)
+	require.Equal(t, []string{"a", "b"}, cfg.Peers)
+	require.True(t, cfg.Section.Enabled)
+	require.Equal(t, map[string]string{"node": "node1"}, cfg.Section.Labels)
+
+	require.Equal(t, map[string]string{
+		"name":             "env TEST_NAME",
+		"nsName":           "file " + filename,
+		"tunnelIP":         "file " + filename,
+		"connectTo":        "file " + filename,
+		"maxTokenLifetime": configloader.SourceDefault,
+		"peers":            "file " + filename,
+		"mirrors":          configloader.SourceDefault,
+		"section.enabled":  "file " + filename,
+		"section.labels":   "file " + filename,
+	}, sources(fields))
+}
+
+func TestLoad_UnknownField(t *testing.T) {
+	filename := writeFile(t, `{"name": "forwarder", "section": {"unknown": 1}}`)
+	_, err := configloader.Load("test", &testConfig{}, filename)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `"section.unknown"`)
+}
+
+func TestLoad_InvalidValue(t *testing.T) {
+	filename := writeFile(t, `maxTokenLifetime: forever`)
+	_, err := configloader.Load("test", &testConfig{}, filename)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `"maxTokenLifetime"`)
+}
+
+func TestPrint(t *testing.T) {
+	fields, err := configloader.Load("test", &testConfig{}, "")
+	require.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, configloader.Print(buf, fields))
+	require.Contains(t, buf.String(), "connectTo")
+	require.Contains(t, buf.String(), "TEST_CONNECT_TO")
+	require.Contains(t, buf.String(), "unix:///connect.to.socket")
+	require.Contains(t, buf.String(), "24h0m0s")
+	require.Contains(t, buf.String(), "[tcp://10.0.0.1:5000 tcp://10.0.0.2:5000]")
+}
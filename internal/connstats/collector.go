@@ -0,0 +1,213 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package connstats periodically reads the VPP interface counters of each active connection, attaches them as
+// metrics to the forwarder's path segment of the connection for MonitorConnection subscribers, and exposes them as
+// Prometheus metrics.  sdk-vppagent is given the counters read rather than reading them again on each Request.
+package connstats
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"go.ligato.io/vpp-agent/v3/proto/ligato/configurator"
+	vppinterfaces "go.ligato.io/vpp-agent/v3/proto/ligato/vpp/interfaces"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/log"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/conntrack"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/ifnames"
+)
+
+// remotePrefix - prefix of the keys of the path segment metrics of the outgoing side of a connection.  The incoming
+// side uses the same unprefixed keys as sdk-vppagent.
+const remotePrefix = "remote_"
+
+// Connections - the active connections, as tracked by conntrack.Server
+type Connections interface {
+	// Connections - returns the incoming connections, as returned to their clients
+	Connections() []*networkservice.Connection
+	// Outgoing - returns the outgoing connection corresponding to the incoming connection conn, if known
+	Outgoing(conn *networkservice.Connection) (*networkservice.Connection, bool)
+}
+
+// Collector - reads the VPP interface counters of the active connections
+type Collector struct {
+	client   configurator.StatsPollerServiceClient
+	conns    Connections
+	interval time.Duration
+
+	mu         sync.RWMutex
+	interfaces map[string]*vppinterfaces.InterfaceStats
+	stats      []*connectionStats
+
+	monitorsMu sync.Mutex
+	monitors   map[*monitor]bool
+}
+
+// connectionStats - the counters of a connection as of the last time they were published
+type connectionStats struct {
+	// conn - the connection as seen by the forwarder, with the counters attached to its path segment
+	conn          *networkservice.Connection
+	local, remote *vppinterfaces.InterfaceStats
+}
+
+// New - returns a new Collector of the counters of conns, read from VPP through client
+func New(client configurator.StatsPollerServiceClient, conns Connections, options ...Option) *Collector {
+	c := &Collector{
+		client:     client,
+		conns:      conns,
+		interval:   defaultInterval,
+		interfaces: make(map[string]*vppinterfaces.InterfaceStats),
+		monitors:   make(map[*monitor]bool),
+	}
+	for _, opt := range options {
+		opt(c)
+	}
+	return c
+}
+
+// Start - starts reading and publishing the counters every interval until ctx is done.  It does nothing if the
+// interval is 0.
+func (c *Collector) Start(ctx context.Context) {
+	if c.interval <= 0 {
+		return
+	}
+	go c.poll(ctx)
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.publish(ctx)
+			}
+		}
+	}()
+}
+
+// poll - keeps c.interfaces up to date with the counters of every VPP interface, polling them again whenever the
+// vppagent goes away
+func (c *Collector) poll(ctx context.Context) {
+	periodSec := uint32(c.interval / time.Second)
+	if periodSec == 0 {
+		periodSec = 1
+	}
+	for ctx.Err() == nil {
+		if err := c.pollStats(ctx, periodSec); err != nil && ctx.Err() == nil {
+			log.Entry(ctx).Debugf("polling VPP interface counters: %+v", err)
+		}
+		// The counters of a vppagent that went away are not those of the next one
+		c.mu.Lock()
+		c.interfaces = make(map[string]*vppinterfaces.InterfaceStats)
+		c.mu.Unlock()
+		select {
+		case <-ctx.Done():
+		case <-time.After(c.interval):
+		}
+	}
+}
+
+func (c *Collector) pollStats(ctx context.Context, periodSec uint32) error {
+	stream, err := c.client.PollStats(ctx, &configurator.PollStatsRequest{PeriodSec: periodSec})
+	if err != nil {
+		return err
+	}
+	// Each poll streams the counters of one interface after the other, all with the same sequence number
+	interfaces := make(map[string]*vppinterfaces.InterfaceStats)
+	var seq uint32
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if resp.GetPollSeq() != seq {
+			c.mu.Lock()
+			c.interfaces = interfaces
+			c.mu.Unlock()
+			interfaces = make(map[string]*vppinterfaces.InterfaceStats)
+			seq = resp.GetPollSeq()
+		}
+		if iface := resp.GetStats().GetVppStats().GetInterface(); iface != nil {
+			interfaces[iface.GetName()] = iface
+		}
+	}
+}
+
+// publish - matches the latest counters with the active connections, and sends them to the MonitorConnection
+// subscribers
+func (c *Collector) publish(ctx context.Context) {
+	c.mu.RLock()
+	interfaces := c.interfaces
+	c.mu.RUnlock()
+
+	var stats []*connectionStats
+	for _, conn := range c.conns.Connections() {
+		own := conntrack.ForwarderView(conn)
+		if own == nil {
+			continue
+		}
+		s := &connectionStats{
+			conn:  own,
+			local: interfaces[ifnames.Server(own.GetMechanism(), own.GetId())],
+		}
+		if outgoing, ok := c.conns.Outgoing(conn); ok {
+			s.remote = interfaces[ifnames.Client(outgoing.GetMechanism(), outgoing.GetId())]
+		}
+		if s.local == nil && s.remote == nil {
+			continue
+		}
+		segment := own.GetPath().GetPathSegments()[own.GetPath().GetIndex()]
+		segment.Metrics = make(map[string]string)
+		addMetrics(segment.Metrics, "", s.local)
+		addMetrics(segment.Metrics, remotePrefix, s.remote)
+		stats = append(stats, s)
+	}
+
+	c.mu.Lock()
+	c.stats = stats
+	c.mu.Unlock()
+
+	if len(stats) == 0 {
+		return
+	}
+	event := &networkservice.ConnectionEvent{
+		Type:        networkservice.ConnectionEventType_UPDATE,
+		Connections: make(map[string]*networkservice.Connection),
+	}
+	for _, s := range stats {
+		event.Connections[s.conn.GetId()] = s.conn
+	}
+	c.send(ctx, event)
+}
+
+func addMetrics(metrics map[string]string, prefix string, stats *vppinterfaces.InterfaceStats) {
+	if stats == nil {
+		return
+	}
+	metrics[prefix+"rx_packets"] = strconv.FormatUint(stats.GetRx().GetPackets(), 10)
+	metrics[prefix+"rx_bytes"] = strconv.FormatUint(stats.GetRx().GetBytes(), 10)
+	metrics[prefix+"tx_packets"] = strconv.FormatUint(stats.GetTx().GetPackets(), 10)
+	metrics[prefix+"tx_bytes"] = strconv.FormatUint(stats.GetTx().GetBytes(), 10)
+	metrics[prefix+"drops"] = strconv.FormatUint(stats.GetDrops(), 10)
+	metrics[prefix+"rx_error_packets"] = strconv.FormatUint(stats.GetRxError(), 10)
+	metrics[prefix+"tx_error_packets"] = strconv.FormatUint(stats.GetTxError(), 10)
+}
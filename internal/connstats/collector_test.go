@@ -0,0 +1,233 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connstats_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/kernel"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/vxlan"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.ligato.io/vpp-agent/v3/proto/ligato/configurator"
+	"go.ligato.io/vpp-agent/v3/proto/ligato/vpp"
+	vppinterfaces "go.ligato.io/vpp-agent/v3/proto/ligato/vpp/interfaces"
+	"google.golang.org/grpc"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/connstats"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/metrics"
+)
+
+type testStatsClient struct {
+	responses chan *configurator.PollStatsResponse
+}
+
+func (c *testStatsClient) PollStats(ctx context.Context, _ *configurator.PollStatsRequest, _ ...grpc.CallOption) (configurator.StatsPollerService_PollStatsClient, error) {
+	return &testStatsStream{ctx: ctx, responses: c.responses}, nil
+}
+
+type testStatsStream struct {
+	configurator.StatsPollerService_PollStatsClient
+	ctx       context.Context
+	responses chan *configurator.PollStatsResponse
+}
+
+func (s *testStatsStream) Recv() (*configurator.PollStatsResponse, error) {
+	select {
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	case resp := <-s.responses:
+		return resp, nil
+	}
+}
+
+type testConnections struct {
+	incoming *networkservice.Connection
+	outgoing *networkservice.Connection
+}
+
+func (c *testConnections) Connections() []*networkservice.Connection {
+	if c.incoming == nil {
+		return nil
+	}
+	return []*networkservice.Connection{c.incoming.Clone()}
+}
+
+func (c *testConnections) Outgoing(*networkservice.Connection) (*networkservice.Connection, bool) {
+	return c.outgoing.Clone(), true
+}
+
+type testMonitorServer struct{}
+
+func (s *testMonitorServer) MonitorConnections(_ *networkservice.MonitorScopeSelector, srv networkservice.MonitorConnection_MonitorConnectionsServer) error {
+	<-srv.Context().Done()
+	return nil
+}
+
+type testMonitorStream struct {
+	networkservice.MonitorConnection_MonitorConnectionsServer
+	ctx    context.Context
+	events chan *networkservice.ConnectionEvent
+}
+
+func (s *testMonitorStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testMonitorStream) Send(event *networkservice.ConnectionEvent) error {
+	s.events <- event
+	return nil
+}
+
+func interfaceStats(seq uint32, name string, packets uint64) *configurator.PollStatsResponse {
+	return &configurator.PollStatsResponse{
+		PollSeq: seq,
+		Stats: &configurator.Stats{Stats: &configurator.Stats_VppStats{VppStats: &vpp.Stats{Interface: &vppinterfaces.InterfaceStats{
+			Name: name,
+			Rx:   &vppinterfaces.InterfaceStats_CombinedCounter{Packets: packets, Bytes: packets * 100},
+			Tx:   &vppinterfaces.InterfaceStats_CombinedCounter{Packets: packets * 2, Bytes: packets * 200},
+		}}}},
+	}
+}
+
+func TestCollector(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := &networkservice.Path{
+		Index: 0,
+		PathSegments: []*networkservice.PathSegment{
+			{Name: "nsc", Id: "nsc-id"},
+			{Name: "forwarder", Id: "fwd-id"},
+		},
+	}
+	conns := &testConnections{
+		incoming: &networkservice.Connection{
+			Id:             "nsc-id",
+			NetworkService: "ns",
+			Mechanism:      &networkservice.Mechanism{Type: kernel.MECHANISM},
+			Path:           path,
+		},
+		outgoing: &networkservice.Connection{
+			Id:        "fwd-id",
+			Mechanism: &networkservice.Mechanism{Type: vxlan.MECHANISM},
+			Path:      &networkservice.Path{Index: 1, PathSegments: path.GetPathSegments()},
+		},
+	}
+	client := &testStatsClient{responses: make(chan *configurator.PollStatsResponse, 10)}
+	client.responses <- interfaceStats(1, "server-fwd-id", 5)
+	client.responses <- interfaceStats(1, "fwd-id", 7)
+	client.responses <- interfaceStats(1, "server-other", 11)
+	client.responses <- interfaceStats(2, "server-fwd-id", 6)
+
+	collector := connstats.New(client, conns, connstats.WithInterval(10*time.Millisecond))
+	forwarderMetrics := metrics.New()
+	forwarderMetrics.Register(collector)
+
+	stream := &testMonitorStream{ctx: ctx, events: make(chan *networkservice.ConnectionEvent, 10)}
+	go func() {
+		_ = collector.MonitorServer(&testMonitorServer{}).MonitorConnections(&networkservice.MonitorScopeSelector{}, stream)
+	}()
+	collector.Start(ctx)
+
+	var event *networkservice.ConnectionEvent
+	select {
+	case event = <-stream.events:
+	case <-time.After(time.Second):
+		require.FailNow(t, "no connection counters received")
+	}
+	require.Equal(t, networkservice.ConnectionEventType_UPDATE, event.GetType())
+	conn, ok := event.GetConnections()["fwd-id"]
+	require.True(t, ok)
+	require.Len(t, event.GetConnections(), 1)
+	require.Equal(t, map[string]string{
+		"rx_packets":              "5",
+		"rx_bytes":                "500",
+		"tx_packets":              "10",
+		"tx_bytes":                "1000",
+		"drops":                   "0",
+		"rx_error_packets":        "0",
+		"tx_error_packets":        "0",
+		"remote_rx_packets":       "7",
+		"remote_rx_bytes":         "700",
+		"remote_tx_packets":       "14",
+		"remote_tx_bytes":         "1400",
+		"remote_drops":            "0",
+		"remote_rx_error_packets": "0",
+		"remote_tx_error_packets": "0",
+	}, conn.GetPath().GetPathSegments()[1].GetMetrics())
+
+	recorder := httptest.NewRecorder()
+	forwarderMetrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", metrics.Path, nil))
+	body, err := ioutil.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body),
+		`forwarder_connection_rx_packets_total{connection_id="fwd-id",interface="server-fwd-id",network_service="ns",side="local"} 5`)
+	require.Contains(t, string(body),
+		`forwarder_connection_tx_bytes_total{connection_id="fwd-id",interface="fwd-id",network_service="ns",side="remote"} 1400`)
+	require.NotContains(t, string(body), "server-other")
+}
+
+type testClientConn struct {
+	grpc.ClientConnInterface
+}
+
+func (cc *testClientConn) NewStream(context.Context, *grpc.StreamDesc, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, errors.New("polled VPP")
+}
+
+func TestCollector_WrapVppagent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := &testStatsClient{responses: make(chan *configurator.PollStatsResponse, 10)}
+	client.responses <- interfaceStats(1, "server-fwd-id", 5)
+	client.responses <- interfaceStats(1, "fwd-id", 7)
+	client.responses <- interfaceStats(2, "server-fwd-id", 6)
+	collector := connstats.New(client, &testConnections{}, connstats.WithInterval(10*time.Millisecond))
+	stats := configurator.NewStatsPollerServiceClient(collector.WrapVppagent(&testClientConn{}))
+
+	// Until the counters have been read once, they are read from VPP
+	_, err := stats.PollStats(ctx, &configurator.PollStatsRequest{})
+	require.EqualError(t, err, "polled VPP")
+
+	collector.Start(ctx)
+	var stream configurator.StatsPollerService_PollStatsClient
+	require.Eventually(t, func() bool {
+		stream, err = stats.PollStats(ctx, &configurator.PollStatsRequest{})
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	var names []string
+	for {
+		resp, recvErr := stream.Recv()
+		if recvErr == io.EOF {
+			break
+		}
+		require.NoError(t, recvErr)
+		names = append(names, resp.GetStats().GetVppStats().GetInterface().GetName())
+		if resp.GetStats().GetVppStats().GetInterface().GetName() == "server-fwd-id" {
+			require.Equal(t, uint64(5), resp.GetStats().GetVppStats().GetInterface().GetRx().GetPackets())
+		}
+	}
+	require.Equal(t, []string{"fwd-id", "server-fwd-id"}, names)
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connstats
+
+import (
+	"context"
+	"sync"
+
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/log"
+)
+
+type monitorServer struct {
+	networkservice.MonitorConnectionServer
+	collector *Collector
+}
+
+// monitor - a MonitorConnections stream, shared by the wrapped MonitorConnectionServer and the Collector
+type monitor struct {
+	networkservice.MonitorConnection_MonitorConnectionsServer
+	selector *networkservice.MonitorScopeSelector
+	mu       sync.Mutex
+}
+
+// MonitorServer - returns a MonitorConnectionServer streaming the events of server, interleaved with UPDATE events
+// carrying the counters of the connections
+func (c *Collector) MonitorServer(server networkservice.MonitorConnectionServer) networkservice.MonitorConnectionServer {
+	return &monitorServer{
+		MonitorConnectionServer: server,
+		collector:               c,
+	}
+}
+
+func (s *monitorServer) MonitorConnections(selector *networkservice.MonitorScopeSelector, srv networkservice.MonitorConnection_MonitorConnectionsServer) error {
+	m := &monitor{
+		MonitorConnection_MonitorConnectionsServer: srv,
+		selector: selector,
+	}
+	s.collector.monitorsMu.Lock()
+	s.collector.monitors[m] = true
+	s.collector.monitorsMu.Unlock()
+	defer func() {
+		s.collector.monitorsMu.Lock()
+		delete(s.collector.monitors, m)
+		s.collector.monitorsMu.Unlock()
+	}()
+	return s.MonitorConnectionServer.MonitorConnections(selector, m)
+}
+
+// Send - serializes the events of the wrapped MonitorConnectionServer with those of the Collector
+func (m *monitor) Send(event *networkservice.ConnectionEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.MonitorConnection_MonitorConnectionsServer.Send(event)
+}
+
+// send - sends event to each monitor, with only the connections its selector selects
+func (c *Collector) send(ctx context.Context, event *networkservice.ConnectionEvent) {
+	c.monitorsMu.Lock()
+	defer c.monitorsMu.Unlock()
+	for m := range c.monitors {
+		connections := networkservice.FilterMapOnManagerScopeSelector(event.Clone().GetConnections(), m.selector)
+		if len(connections) == 0 {
+			continue
+		}
+		if err := m.Send(&networkservice.ConnectionEvent{Type: event.GetType(), Connections: connections}); err != nil {
+			log.Entry(ctx).Debugf("error sending connection counters: %+v", err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connstats
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	vppinterfaces "go.ligato.io/vpp-agent/v3/proto/ligato/vpp/interfaces"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/metrics"
+)
+
+var (
+	counterLabels = []string{"connection_id", "network_service", "side", "interface"}
+	counters      = []struct {
+		desc  *prometheus.Desc
+		value func(stats *vppinterfaces.InterfaceStats) uint64
+	}{
+		{newDesc("rx_packets_total", "Packets received"), func(s *vppinterfaces.InterfaceStats) uint64 { return s.GetRx().GetPackets() }},
+		{newDesc("rx_bytes_total", "Bytes received"), func(s *vppinterfaces.InterfaceStats) uint64 { return s.GetRx().GetBytes() }},
+		{newDesc("tx_packets_total", "Packets transmitted"), func(s *vppinterfaces.InterfaceStats) uint64 { return s.GetTx().GetPackets() }},
+		{newDesc("tx_bytes_total", "Bytes transmitted"), func(s *vppinterfaces.InterfaceStats) uint64 { return s.GetTx().GetBytes() }},
+		{newDesc("drops_total", "Packets dropped"), func(s *vppinterfaces.InterfaceStats) uint64 { return s.GetDrops() }},
+		{newDesc("rx_errors_total", "Receive errors"), func(s *vppinterfaces.InterfaceStats) uint64 { return s.GetRxError() }},
+		{newDesc("tx_errors_total", "Transmit errors"), func(s *vppinterfaces.InterfaceStats) uint64 { return s.GetTxError() }},
+	}
+)
+
+func newDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "connection", name),
+		help+" by the VPP interface of a connection, on its local (incoming) or remote (outgoing) side",
+		counterLabels, nil)
+}
+
+// Describe - implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, counter := range counters {
+		ch <- counter.desc
+	}
+}
+
+// Collect - implements prometheus.Collector, with the counters as of the last time they were published
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	stats := c.stats
+	c.mu.RUnlock()
+	for _, s := range stats {
+		for side, iface := range map[string]*vppinterfaces.InterfaceStats{"local": s.local, "remote": s.remote} {
+			if iface == nil {
+				continue
+			}
+			for _, counter := range counters {
+				ch <- prometheus.MustNewConstMetric(counter.desc, prometheus.CounterValue, float64(counter.value(iface)),
+					s.conn.GetId(), s.conn.GetNetworkService(), side, iface.GetName())
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connstats
+
+import (
+	"context"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+	"go.ligato.io/vpp-agent/v3/proto/ligato/configurator"
+	"go.ligato.io/vpp-agent/v3/proto/ligato/vpp"
+	vppinterfaces "go.ligato.io/vpp-agent/v3/proto/ligato/vpp/interfaces"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// pollStatsMethod - full name of the method of the vppagent streaming the counters of the VPP interfaces
+const pollStatsMethod = "/ligato.configurator.StatsPollerService/PollStats"
+
+type vppagentClientConn struct {
+	grpc.ClientConnInterface
+	collector *Collector
+}
+
+// WrapVppagent - returns a grpc.ClientConnInterface to the vppagent reached by cc that answers PollStats with the
+// counters c last read, rather than having VPP read them once more, as sdk-vppagent does on each Request.  PollStats
+// reaches the vppagent as long as c has not read any.
+func (c *Collector) WrapVppagent(cc grpc.ClientConnInterface) grpc.ClientConnInterface {
+	return &vppagentClientConn{
+		ClientConnInterface: cc,
+		collector:           c,
+	}
+}
+
+func (c *vppagentClientConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	if method == pollStatsMethod {
+		c.collector.mu.RLock()
+		interfaces := c.collector.interfaces
+		c.collector.mu.RUnlock()
+		if len(interfaces) > 0 {
+			return newStatsStream(ctx, interfaces), nil
+		}
+	}
+	return c.ClientConnInterface.NewStream(ctx, desc, method, opts...)
+}
+
+// statsStream - a PollStats stream of a single poll of the counters already read
+type statsStream struct {
+	ctx       context.Context
+	responses []*configurator.PollStatsResponse
+}
+
+func newStatsStream(ctx context.Context, interfaces map[string]*vppinterfaces.InterfaceStats) *statsStream {
+	s := &statsStream{ctx: ctx}
+	for _, iface := range interfaces {
+		s.responses = append(s.responses, &configurator.PollStatsResponse{
+			Stats: &configurator.Stats{Stats: &configurator.Stats_VppStats{VppStats: &vpp.Stats{Interface: iface}}},
+		})
+	}
+	sort.Slice(s.responses, func(i, j int) bool {
+		return s.responses[i].GetStats().GetVppStats().GetInterface().GetName() < s.responses[j].GetStats().GetVppStats().GetInterface().GetName()
+	})
+	return s
+}
+
+func (s *statsStream) Header() (metadata.MD, error) {
+	return nil, nil
+}
+
+func (s *statsStream) Trailer() metadata.MD {
+	return nil
+}
+
+func (s *statsStream) CloseSend() error {
+	return nil
+}
+
+func (s *statsStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *statsStream) SendMsg(interface{}) error {
+	return nil
+}
+
+// RecvMsg - receives the counters of the next interface, and io.EOF once all of them have been received
+func (s *statsStream) RecvMsg(m interface{}) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if len(s.responses) == 0 {
+		return io.EOF
+	}
+	resp, ok := m.(*configurator.PollStatsResponse)
+	if !ok {
+		return errors.Errorf("unexpected PollStats response type %T", m)
+	}
+	resp.PollSeq, resp.Stats = s.responses[0].GetPollSeq(), s.responses[0].GetStats()
+	s.responses = s.responses[1:]
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conntrack
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"google.golang.org/grpc"
+)
+
+const (
+	requestMethod = "/networkservice.NetworkService/Request"
+	closeMethod   = "/networkservice.NetworkService/Close"
+)
+
+// UnaryClientInterceptor - returns an interceptor to dial the NSMgr with, so that s also keeps track of the outgoing
+// connection corresponding to each incoming one
+func (s *Server) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		switch method {
+		case requestMethod:
+			if conn, ok := reply.(*networkservice.Connection); ok && err == nil {
+				s.mu.Lock()
+				s.outgoing[ownSegmentID(conn, 0)] = proto.Clone(conn).(*networkservice.Connection)
+				s.mu.Unlock()
+			}
+		case closeMethod:
+			if conn, ok := req.(*networkservice.Connection); ok {
+				s.mu.Lock()
+				delete(s.outgoing, ownSegmentID(conn, 0))
+				s.mu.Unlock()
+			}
+		}
+		return err
+	}
+}
+
+// Outgoing - returns a copy of the outgoing connection corresponding to the incoming connection conn, if known
+func (s *Server) Outgoing(conn *networkservice.Connection) (*networkservice.Connection, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	outgoing, ok := s.outgoing[ownSegmentID(conn, 1)]
+	if !ok {
+		return nil, false
+	}
+	return proto.Clone(outgoing).(*networkservice.Connection), true
+}
+
+// ForwarderView - returns a copy of the incoming connection conn as seen by the forwarder rather than by its client,
+// which is how the forwarder reports it to MonitorConnection subscribers, or nil if conn has no forwarder segment
+func ForwarderView(conn *networkservice.Connection) *networkservice.Connection {
+	conn = proto.Clone(conn).(*networkservice.Connection)
+	path := conn.GetPath()
+	if int(path.GetIndex())+1 >= len(path.GetPathSegments()) {
+		return nil
+	}
+	path.Index++
+	conn.Id = path.GetPathSegments()[path.GetIndex()].GetId()
+	return conn
+}
+
+// ownSegmentID - returns the id of the path segment of the forwarder in conn, which is offset segments past its
+// current index: 1 for an incoming connection as returned to the client, 0 for an outgoing one as returned by the
+// NSMgr
+func ownSegmentID(conn *networkservice.Connection, offset int) string {
+	path := conn.GetPath()
+	index := int(path.GetIndex()) + offset
+	if index >= len(path.GetPathSegments()) {
+		return ""
+	}
+	return path.GetPathSegments()[index].GetId()
+}
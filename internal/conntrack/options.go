@@ -0,0 +1,28 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conntrack
+
+// Option - option for use with NewServer(...)
+type Option func(s *Server)
+
+// WithStateFile - persists the tracked connections to stateFile whenever they change, for Restore(...) to restore
+// them in the next forwarder instance
+func WithStateFile(stateFile string) Option {
+	return func(s *Server) {
+		s.stateFile = stateFile
+	}
+}
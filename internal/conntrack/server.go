@@ -0,0 +1,267 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package conntrack provides a NetworkServiceServer chain element that keeps track of the connections that have been
+// successfully Requested and not yet Closed through it
+package conntrack
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/pkg/errors"
+
+	"github.com/networkservicemesh/sdk/pkg/networkservice/core/next"
+	"github.com/networkservicemesh/sdk/pkg/tools/log"
+)
+
+// Server - NetworkServiceServer chain element keeping track of active connections
+type Server struct {
+	stateFile string
+
+	mu       sync.RWMutex
+	entries  map[string]*entry
+	outgoing map[string]*networkservice.Connection
+	saveMu   sync.Mutex
+}
+
+type entry struct {
+	conn      *networkservice.Connection
+	created   time.Time
+	refreshed time.Time
+}
+
+// NewServer - returns a new Server.  It should be placed before any chain element that may modify the connection
+// on its way back to the client, so that what it tracks is exactly what the client sees and will Close.
+func NewServer(options ...Option) *Server {
+	s := &Server{
+		entries:  make(map[string]*entry),
+		outgoing: make(map[string]*networkservice.Connection),
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// Request - tracks the connection returned by the rest of the chain
+func (s *Server) Request(ctx context.Context, request *networkservice.NetworkServiceRequest) (*networkservice.Connection, error) {
+	conn, err := next.Server(ctx).Request(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	s.mu.Lock()
+	e, ok := s.entries[conn.GetId()]
+	if !ok {
+		e = &entry{created: now}
+		s.entries[conn.GetId()] = e
+	}
+	e.conn = proto.Clone(conn).(*networkservice.Connection)
+	e.refreshed = now
+	s.mu.Unlock()
+	if err := s.save(); err != nil {
+		log.Entry(ctx).Errorf("%+v", err)
+	}
+	return conn, nil
+}
+
+// Close - stops tracking conn
+func (s *Server) Close(ctx context.Context, conn *networkservice.Connection) (*empty.Empty, error) {
+	s.mu.Lock()
+	delete(s.entries, conn.GetId())
+	delete(s.outgoing, ownSegmentID(conn, 1))
+	s.mu.Unlock()
+	if err := s.save(); err != nil {
+		log.Entry(ctx).Errorf("%+v", err)
+	}
+	return next.Server(ctx).Close(ctx, conn)
+}
+
+// Entry - a tracked connection
+type Entry struct {
+	// Connection - the incoming connection, as returned to the client
+	Connection *networkservice.Connection
+	// Outgoing - the corresponding outgoing connection, as returned by the NSMgr, if known
+	Outgoing  *networkservice.Connection
+	Created   time.Time
+	Refreshed time.Time
+}
+
+// Entries - returns copies of the tracked connections along with what else is known of them, ordered by creation
+// time
+func (s *Server) Entries() []*Entry {
+	s.prune()
+	s.mu.RLock()
+	entries := make([]*Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entry := &Entry{
+			Connection: proto.Clone(e.conn).(*networkservice.Connection),
+			Created:    e.created,
+			Refreshed:  e.refreshed,
+		}
+		if outgoing, ok := s.outgoing[ownSegmentID(e.conn, 1)]; ok {
+			entry.Outgoing = proto.Clone(outgoing).(*networkservice.Connection)
+		}
+		entries = append(entries, entry)
+	}
+	s.mu.RUnlock()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Created.Before(entries[j].Created) })
+	return entries
+}
+
+// Connections - returns copies of the tracked connections, ordered by creation time
+func (s *Server) Connections() []*networkservice.Connection {
+	entries := s.Entries()
+	conns := make([]*networkservice.Connection, 0, len(entries))
+	for _, e := range entries {
+		conns = append(conns, e.Connection)
+	}
+	return conns
+}
+
+// Tracks - returns whether the connection conn, as seen by the client, is tracked
+func (s *Server) Tracks(conn *networkservice.Connection) bool {
+	s.prune()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.entries[conn.GetId()]
+	return ok
+}
+
+// Len - returns the number of tracked connections
+func (s *Server) Len() int {
+	s.prune()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
+
+// Replay - Requests each tracked connection again through server, which should be the chain containing s, so that
+// its configuration is reapplied to the dataplane.  Failing to replay one connection does not stop the others from
+// being replayed.
+func (s *Server) Replay(ctx context.Context, server networkservice.NetworkServiceServer) error {
+	conns := s.Connections()
+	var failed []string
+	for _, conn := range conns {
+		if err := request(ctx, server, conn); err != nil {
+			log.Entry(ctx).Errorf("failed to replay connection %s: %+v", conn.GetId(), err)
+			failed = append(failed, conn.GetId())
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("failed to replay %d of %d connections: %v", len(failed), len(conns), failed)
+	}
+	return nil
+}
+
+// Persisted - returns the connections persisted in the state file by a previous forwarder instance that have not
+// expired yet, and would therefore be restored by Restore(...)
+func (s *Server) Persisted() ([]*networkservice.Connection, error) {
+	_, conns, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var persisted []*networkservice.Connection
+	for _, conn := range conns {
+		if expires, ok := expiresAt(conn); !ok || !expires.Before(time.Now()) {
+			persisted = append(persisted, conn)
+		}
+	}
+	return persisted, nil
+}
+
+// Restore - Requests each connection persisted in the state file by a previous forwarder instance through server,
+// which should be the chain containing s, so that it is recreated in the dataplane and tracked again.  Connections
+// that have expired in the meantime are dropped.  It returns the number of connections restored.
+func (s *Server) Restore(ctx context.Context, server networkservice.NetworkServiceServer) (int, error) {
+	entries, conns, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+	restored := 0
+	for i, conn := range conns {
+		if expires, ok := expiresAt(conn); ok && expires.Before(time.Now()) {
+			log.Entry(ctx).Infof("not restoring expired connection %s", conn.GetId())
+			continue
+		}
+		if err = request(ctx, server, conn); err != nil {
+			log.Entry(ctx).Errorf("failed to restore connection %s: %+v", conn.GetId(), err)
+			continue
+		}
+		s.mu.Lock()
+		if e, ok := s.entries[conn.GetId()]; ok {
+			e.created = entries[i].Created
+		}
+		s.mu.Unlock()
+		restored++
+	}
+	// Rewrite the state file, so that connections that could not be restored are forgotten
+	return restored, s.save()
+}
+
+// prune - forgets connections the client has stopped refreshing.  The chain times those out and closes them
+// internally, without the Close ever passing through this element.
+func (s *Server) prune() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, e := range s.entries {
+		if expires, ok := expiresAt(e.conn); ok && expires.Before(now) {
+			delete(s.entries, id)
+			delete(s.outgoing, ownSegmentID(e.conn, 1))
+		}
+	}
+}
+
+type replayKey struct{}
+
+// IsReplay - returns whether ctx is that of a Request replaying or restoring a tracked connection, rather than one
+// from a client.  Such a Request carries no peer, and the connection was admitted when first Requested.
+func IsReplay(ctx context.Context) bool {
+	return ctx.Value(replayKey{}) != nil
+}
+
+// request - Requests conn through server, for no longer than conn remains valid, with a ctx marked for IsReplay
+func request(ctx context.Context, server networkservice.NetworkServiceServer, conn *networkservice.Connection) error {
+	ctx = context.WithValue(ctx, replayKey{}, true)
+	if expires, ok := expiresAt(conn); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, expires)
+		defer cancel()
+	}
+	_, err := server.Request(ctx, &networkservice.NetworkServiceRequest{Connection: conn})
+	return err
+}
+
+// expiresAt - returns the expiration time of the token of the client's path segment of conn
+func expiresAt(conn *networkservice.Connection) (time.Time, bool) {
+	path := conn.GetPath()
+	if int(path.GetIndex()) >= len(path.GetPathSegments()) {
+		return time.Time{}, false
+	}
+	expires, err := ptypes.Timestamp(path.GetPathSegments()[path.GetIndex()].GetExpires())
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expires, true
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conntrack_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/stretchr/testify/require"
+
+	"github.com/networkservicemesh/sdk/pkg/networkservice/core/chain"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/conntrack"
+)
+
+func connection(t *testing.T, id string, expires time.Time) *networkservice.Connection {
+	expiresProto, err := ptypes.TimestampProto(expires)
+	require.NoError(t, err)
+	return &networkservice.Connection{
+		Id:             id,
+		NetworkService: "ns",
+		Path: &networkservice.Path{
+			PathSegments: []*networkservice.PathSegment{
+				{Name: "nsmgr", Id: id, Expires: expiresProto},
+			},
+		},
+	}
+}
+
+func TestServer_Restore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "conntrack-")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	stateFile := filepath.Join(dir, "state.json")
+	ctx := context.Background()
+
+	tracker := conntrack.NewServer(conntrack.WithStateFile(stateFile))
+	server := chain.NewNetworkServiceServer(tracker)
+	for _, conn := range []*networkservice.Connection{
+		connection(t, "live", time.Now().Add(time.Hour)),
+		connection(t, "closed", time.Now().Add(time.Hour)),
+		connection(t, "expiring", time.Now().Add(100*time.Millisecond)),
+	} {
+		_, err = server.Request(ctx, &networkservice.NetworkServiceRequest{Connection: conn})
+		require.NoError(t, err)
+	}
+	_, err = server.Close(ctx, connection(t, "closed", time.Now().Add(time.Hour)))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return tracker.Len() == 1 }, time.Second, 10*time.Millisecond)
+
+	restoredTracker := conntrack.NewServer(conntrack.WithStateFile(stateFile))
+	restored, err := restoredTracker.Restore(ctx, chain.NewNetworkServiceServer(restoredTracker))
+	require.NoError(t, err)
+	require.Equal(t, 1, restored)
+	conns := restoredTracker.Connections()
+	require.Len(t, conns, 1)
+	require.Equal(t, "live", conns[0].GetId())
+	require.Equal(t, "ns", conns[0].GetNetworkService())
+}
+
+func TestServer_RestoreWithoutStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "conntrack-")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	tracker := conntrack.NewServer(conntrack.WithStateFile(filepath.Join(dir, "state.json")))
+	restored, err := tracker.Restore(context.Background(), chain.NewNetworkServiceServer(tracker))
+	require.NoError(t, err)
+	require.Equal(t, 0, restored)
+}
+
+// replayRecorder - records whether each Request it handles is a replay
+type replayRecorder []bool
+
+func (r *replayRecorder) Request(ctx context.Context, request *networkservice.NetworkServiceRequest) (*networkservice.Connection, error) {
+	*r = append(*r, conntrack.IsReplay(ctx))
+	return request.GetConnection(), nil
+}
+
+func (r *replayRecorder) Close(context.Context, *networkservice.Connection) (*empty.Empty, error) {
+	return &empty.Empty{}, nil
+}
+
+func TestServer_Replay(t *testing.T) {
+	tracker := conntrack.NewServer()
+	recorder := &replayRecorder{}
+	server := chain.NewNetworkServiceServer(tracker, recorder)
+
+	_, err := server.Request(context.Background(), &networkservice.NetworkServiceRequest{
+		Connection: connection(t, "id", time.Now().Add(time.Hour)),
+	})
+	require.NoError(t, err)
+	require.NoError(t, tracker.Replay(context.Background(), server))
+	require.Equal(t, []bool{false, true}, []bool(*recorder))
+}
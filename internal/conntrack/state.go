@@ -0,0 +1,113 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conntrack
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/pkg/errors"
+)
+
+// state - contents of the state file
+type state struct {
+	Connections []*stateEntry `json:"connections"`
+}
+
+type stateEntry struct {
+	Created    time.Time       `json:"created"`
+	Refreshed  time.Time       `json:"refreshed"`
+	Connection json.RawMessage `json:"connection"`
+}
+
+// save - writes all tracked connections to the state file, if there is one
+func (s *Server) save() error {
+	if s.stateFile == "" {
+		return nil
+	}
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	st := &state{}
+	marshaler := &jsonpb.Marshaler{}
+	s.mu.RLock()
+	for _, e := range s.entries {
+		conn, err := marshaler.MarshalToString(e.conn)
+		if err != nil {
+			s.mu.RUnlock()
+			return errors.Wrapf(err, "failed to marshal connection %s", e.conn.GetId())
+		}
+		st.Connections = append(st.Connections, &stateEntry{
+			Created:    e.created,
+			Refreshed:  e.refreshed,
+			Connection: json.RawMessage(conn),
+		})
+	}
+	s.mu.RUnlock()
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// Write to a temporary file first, so that the state file is replaced atomically and never left half written
+	tmpFile, err := ioutil.TempFile(filepath.Dir(s.stateFile), filepath.Base(s.stateFile)+".*")
+	if err != nil {
+		return errors.Wrapf(err, "failed to save state to %s", s.stateFile)
+	}
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+	if _, err = tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		return errors.Wrapf(err, "failed to save state to %s", s.stateFile)
+	}
+	if err = tmpFile.Close(); err != nil {
+		return errors.Wrapf(err, "failed to save state to %s", s.stateFile)
+	}
+	return errors.Wrapf(os.Rename(tmpFile.Name(), s.stateFile), "failed to save state to %s", s.stateFile)
+}
+
+// load - reads the connections from the state file, if there is one
+func (s *Server) load() ([]*stateEntry, []*networkservice.Connection, error) {
+	if s.stateFile == "" {
+		return nil, nil, nil
+	}
+	data, err := ioutil.ReadFile(s.stateFile)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to read state from %s", s.stateFile)
+	}
+	st := &state{}
+	if err = json.Unmarshal(data, st); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to parse state from %s", s.stateFile)
+	}
+	conns := make([]*networkservice.Connection, 0, len(st.Connections))
+	for _, e := range st.Connections {
+		conn := &networkservice.Connection{}
+		if err = jsonpb.Unmarshal(bytes.NewReader(e.Connection), conn); err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to parse state from %s", s.stateFile)
+		}
+		conns = append(conns, conn)
+	}
+	return st.Connections, conns, nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package drain puts the forwarder into drain mode ahead of node maintenance: it stops accepting new connections,
+// so that they get routed to other forwarders, while keeping the existing ones alive
+package drain
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/log"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/httpserve"
+)
+
+// Path - HTTP path on which the drain status is served, and a POST starts draining
+const Path = "/drain"
+
+// Gate - stops accepting new connections once drained, as gate.Server does
+type Gate interface {
+	Drain()
+}
+
+// Registrar - keeps the forwarder registered with the registry until unregistered, as registration.Registrar does
+type Registrar interface {
+	Unregister(ctx context.Context) error
+}
+
+// Status - the drain status of the forwarder
+type Status struct {
+	Draining bool       `json:"draining"`
+	Since    *time.Time `json:"since,omitempty"`
+	// Connections - number of connections remaining
+	Connections int `json:"connections"`
+}
+
+// Controller - drains the forwarder on demand
+type Controller struct {
+	gate        Gate
+	registrar   Registrar
+	connections func() int
+
+	mu    sync.Mutex
+	since *time.Time
+}
+
+// New - returns a Controller draining gate and unregistering registrar, which reports the number of connections
+// remaining as returned by connections
+func New(gate Gate, registrar Registrar, connections func() int) *Controller {
+	return &Controller{
+		gate:        gate,
+		registrar:   registrar,
+		connections: connections,
+	}
+}
+
+// Drain - rejects any further Request for a new connection, and unregisters the forwarder so that the NSMgr routes
+// them elsewhere.  Existing connections are still refreshed and Closed as usual.  Draining again only retries
+// unregistering, should it have failed.
+func (c *Controller) Drain(ctx context.Context) (*Status, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.since == nil {
+		now := time.Now()
+		c.since = &now
+		c.gate.Drain()
+		log.Entry(ctx).Infof("draining, %d connections remaining", c.connections())
+	}
+	if err := c.registrar.Unregister(ctx); err != nil {
+		return c.status(), err
+	}
+	return c.status(), nil
+}
+
+// Status - returns the current drain status
+func (c *Controller) Status() *Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status()
+}
+
+// Handler - returns an http.Handler serving the drain status on Path, and draining on a POST to it
+func (c *Controller) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(Path, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			httpserve.WriteJSON(w, http.StatusOK, c.Status())
+		case http.MethodPost:
+			status, err := c.Drain(r.Context())
+			if err != nil {
+				log.Entry(r.Context()).Errorf("%+v", err)
+				httpserve.WriteJSON(w, http.StatusBadGateway, &struct {
+					*Status
+					Error string `json:"error"`
+				}{Status: status, Error: err.Error()})
+				return
+			}
+			httpserve.WriteJSON(w, http.StatusOK, status)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	})
+	return mux
+}
+
+// status - must be called with c.mu locked
+func (c *Controller) status() *Status {
+	return &Status{
+		Draining:    c.since != nil,
+		Since:       c.since,
+		Connections: c.connections(),
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package drain_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/networkservicemesh/sdk/pkg/networkservice/core/chain"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/drain"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/gate"
+)
+
+type testRegistrar struct {
+	err          error
+	unregistered int
+}
+
+func (r *testRegistrar) Unregister(context.Context) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.unregistered++
+	return nil
+}
+
+func serve(t *testing.T, handler http.Handler, method string) (int, *drain.Status) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, drain.Path, nil))
+	s := &drain.Status{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), s))
+	return recorder.Code, s
+}
+
+func request(id string) *networkservice.NetworkServiceRequest {
+	return &networkservice.NetworkServiceRequest{Connection: &networkservice.Connection{Id: id}}
+}
+
+func TestController(t *testing.T) {
+	ctx := context.Background()
+	gateServer := gate.NewServer(gate.WithActiveFunc(func(conn *networkservice.Connection) bool {
+		return conn.GetId() == "active"
+	}))
+	server := chain.NewNetworkServiceServer(gateServer)
+	registrar := &testRegistrar{err: errors.New("registry unreachable")}
+	controller := drain.New(gateServer, registrar, func() int { return 1 })
+	handler := controller.Handler()
+
+	_, err := server.Request(ctx, request("new"))
+	require.NoError(t, err)
+	code, s := serve(t, handler, http.MethodGet)
+	require.Equal(t, http.StatusOK, code)
+	require.False(t, s.Draining)
+
+	code, s = serve(t, handler, http.MethodPost)
+	require.Equal(t, http.StatusBadGateway, code)
+	require.True(t, s.Draining)
+	require.Equal(t, 1, s.Connections)
+	require.True(t, gateServer.Draining())
+
+	_, err = server.Request(ctx, request("new"))
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	_, err = server.Request(ctx, request("active"))
+	require.NoError(t, err)
+	_, err = server.Close(ctx, request("active").GetConnection())
+	require.NoError(t, err)
+
+	registrar.err = nil
+	code, _ = serve(t, handler, http.MethodPost)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, 1, registrar.unregistered)
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package failover provides the grpc.DialOptions to reach a service through several endpoints, either spreading the
+// calls over those that are healthy or failing over between them in order of priority
+package failover
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	// Registers the client side health checking used by the RoundRobin policy
+	_ "google.golang.org/grpc/health"
+	"google.golang.org/grpc/resolver"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/grpcutils"
+)
+
+const (
+	// Scheme - scheme of the URLs returned by URL(...)
+	Scheme = "failover"
+	// RoundRobin - policy spreading the calls over all the endpoints whose grpc health service reports them as
+	// serving
+	RoundRobin = "round-robin"
+	// Priority - policy sending the calls to the first endpoint that can be connected to, in the order given, until
+	// the connection to it is lost
+	Priority = "priority"
+)
+
+var serviceConfigs = map[string]string{
+	RoundRobin: `{"loadBalancingConfig": [{"round_robin": {}}], "healthCheckConfig": {"serviceName": ""}}`,
+	Priority:   `{"loadBalancingConfig": [{"pick_first": {}}]}`,
+}
+
+// URL - returns the URL to dial, with the options returned by DialOptions(...), for the service called name
+func URL(name string) *url.URL {
+	return &url.URL{Scheme: Scheme, Path: "/" + name}
+}
+
+// DialOptions - returns the options with which dialing URL(...) reaches one of the endpoints at urls, chosen
+// according to policy: RoundRobin or Priority
+func DialOptions(urls []url.URL, policy string, options ...Option) ([]grpc.DialOption, error) {
+	serviceConfig, ok := serviceConfigs[policy]
+	if !ok {
+		return nil, errors.Errorf("unknown policy %q, expected %s or %s", policy, RoundRobin, Priority)
+	}
+	if len(urls) == 0 {
+		return nil, errors.New("no endpoints to connect to")
+	}
+	b := &builder{}
+	for i := range urls {
+		b.addresses = append(b.addresses, resolver.Address{Addr: grpcutils.URLToTarget(&urls[i])})
+	}
+	d := &dialer{reconnectFunc: func(string) {}}
+	for _, opt := range options {
+		opt(d)
+	}
+	return []grpc.DialOption{
+		grpc.WithResolvers(b),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithContextDialer(d.dial),
+	}, nil
+}
+
+// String - returns urls as a comma separated list, for display
+func String(urls []url.URL) string {
+	rv := make([]string, len(urls))
+	for i := range urls {
+		rv[i] = urls[i].String()
+	}
+	return strings.Join(rv, ",")
+}
+
+// builder - resolves the URLs of Scheme to its fixed list of addresses
+type builder struct {
+	addresses []resolver.Address
+}
+
+func (b *builder) Build(_ resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	cc.UpdateState(resolver.State{Addresses: b.addresses})
+	return b, nil
+}
+
+func (b *builder) Scheme() string {
+	return Scheme
+}
+
+func (b *builder) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (b *builder) Close() {}
+
+// dialer - dials the addresses of the endpoints, reporting each connection established after the first
+type dialer struct {
+	reconnectFunc func(address string)
+
+	mu        sync.Mutex
+	connected bool
+}
+
+func (d *dialer) dial(ctx context.Context, address string) (net.Conn, error) {
+	network, addr := grpcutils.TargetToNetAddr(address)
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to %s", address)
+	}
+	d.mu.Lock()
+	reconnected := d.connected
+	d.connected = true
+	d.mu.Unlock()
+	if reconnected {
+		d.reconnectFunc(address)
+	}
+	return conn, nil
+}
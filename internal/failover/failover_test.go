@@ -0,0 +1,131 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package failover_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/grpcutils"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/failover"
+)
+
+// endpoint - a grpc server whose health service reports its own name as a serving service
+type endpoint struct {
+	url    url.URL
+	health *health.Server
+	server *grpc.Server
+}
+
+func startEndpoints(t *testing.T, names ...string) ([]*endpoint, []url.URL) {
+	dir, err := ioutil.TempDir("", "failover")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var endpoints []*endpoint
+	var urls []url.URL
+	for _, name := range names {
+		e := &endpoint{
+			url:    url.URL{Scheme: "unix", Path: filepath.Join(dir, name+".sock")},
+			health: health.NewServer(),
+			server: grpc.NewServer(),
+		}
+		e.health.SetServingStatus(name, grpc_health_v1.HealthCheckResponse_SERVING)
+		grpc_health_v1.RegisterHealthServer(e.server, e.health)
+		grpcutils.ListenAndServe(ctx, &e.url, e.server)
+		t.Cleanup(e.server.Stop)
+		endpoints = append(endpoints, e)
+		urls = append(urls, e.url)
+	}
+	return endpoints, urls
+}
+
+func dial(t *testing.T, urls []url.URL, policy string, options ...failover.Option) grpc_health_v1.HealthClient {
+	dialOptions, err := failover.DialOptions(urls, policy, options...)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cc, err := grpc.DialContext(ctx, failover.URL("test").String(),
+		append(dialOptions, grpc.WithInsecure(), grpc.WithBlock())...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = cc.Close() })
+	return grpc_health_v1.NewHealthClient(cc)
+}
+
+// served - returns whether the call for service reached the endpoint of that name
+func served(client grpc_health_v1.HealthClient, service string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service}, grpc.WaitForReady(true))
+	return err == nil
+}
+
+func TestDialOptions_Priority(t *testing.T) {
+	endpoints, urls := startEndpoints(t, "a", "b")
+	reconnectCh := make(chan string, 1)
+	client := dial(t, urls, failover.Priority, failover.WithReconnectFunc(func(address string) {
+		select {
+		case reconnectCh <- address:
+		default:
+		}
+	}))
+
+	for i := 0; i < 5; i++ {
+		require.True(t, served(client, "a"))
+	}
+
+	endpoints[0].server.Stop()
+	require.Eventually(t, func() bool { return served(client, "b") }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, endpoints[1].url.String(), <-reconnectCh)
+}
+
+func TestDialOptions_RoundRobin(t *testing.T) {
+	endpoints, urls := startEndpoints(t, "a", "b")
+	client := dial(t, urls, failover.RoundRobin)
+
+	require.Eventually(t, func() bool { return served(client, "a") && served(client, "b") }, 5*time.Second, 10*time.Millisecond)
+
+	endpoints[1].health.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	require.Eventually(t, func() bool {
+		for i := 0; i < 4; i++ {
+			if !served(client, "a") {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestDialOptions_Invalid(t *testing.T) {
+	_, err := failover.DialOptions([]url.URL{{Scheme: "unix", Path: "/a.sock"}}, "random")
+	require.Error(t, err)
+	_, err = failover.DialOptions(nil, failover.Priority)
+	require.Error(t, err)
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package failover
+
+// Option - option for use with DialOptions(...)
+type Option func(d *dialer)
+
+// WithReconnectFunc - sets a function to be called with the address of the endpoint each time a connection is
+// established after the very first one, as happens when failing over to another endpoint
+func WithReconnectFunc(reconnectFunc func(address string)) Option {
+	return func(d *dialer) {
+		d.reconnectFunc = reconnectFunc
+	}
+}
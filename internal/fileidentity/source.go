@@ -0,0 +1,104 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fileidentity provides the X509-SVID and trust bundle of the forwarder from files, as an alternative to the
+// SPIRE agent's Workload API
+package fileidentity
+
+import (
+	"context"
+	"crypto/x509"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/log"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/filewatch"
+)
+
+// Source - an x509svid.Source and x509bundle.Source backed by PEM files
+type Source struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu          sync.RWMutex
+	svid        *x509svid.SVID
+	authorities []*x509.Certificate
+}
+
+// New - returns a Source with the X509-SVID in certFile and keyFile, trusting the CA certificates in caFile.  The
+// certificate must carry the SPIFFE ID of the forwarder as its URI SAN.
+func New(certFile, keyFile, caFile string) (*Source, error) {
+	s := &Source{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+	}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetX509SVID - returns the current X509-SVID
+func (s *Source) GetX509SVID() (*x509svid.SVID, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.svid, nil
+}
+
+// GetX509BundleForTrustDomain - returns the CA certificates as the bundle of trustDomain.  The CA file is the only
+// trust anchor, so it is used for any trust domain, the peers being restricted by their SPIFFE IDs instead.
+func (s *Source) GetX509BundleForTrustDomain(trustDomain spiffeid.TrustDomain) (*x509bundle.Bundle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return x509bundle.FromX509Authorities(trustDomain, s.authorities), nil
+}
+
+// Watch - reloads s whenever one of its files changes, until ctx is done.  Should the changed files fail to load, for
+// instance while only one of the certificate and key has been rotated yet, the previous ones stay in use.
+func (s *Source) Watch(ctx context.Context) error {
+	return filewatch.Watch(ctx, "identity", func() {
+		if err := s.reload(); err != nil {
+			log.Entry(ctx).Warnf("keeping previous identity: %+v", err)
+			return
+		}
+		svid, _ := s.GetX509SVID()
+		log.Entry(ctx).Infof("reloaded identity %s, valid until %s", svid.ID, svid.Certificates[0].NotAfter)
+	}, s.certFile, s.keyFile, s.caFile)
+}
+
+// reload - loads all of the files, and only replaces the current identity if they all loaded
+func (s *Source) reload() error {
+	svid, err := x509svid.Load(s.certFile, s.keyFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load certificate %s and key %s", s.certFile, s.keyFile)
+	}
+	bundle, err := x509bundle.Load(svid.ID.TrustDomain(), s.caFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to load CA certificates %s", s.caFile)
+	}
+	s.mu.Lock()
+	s.svid = svid
+	s.authorities = bundle.X509Authorities()
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fileidentity_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/fileidentity"
+)
+
+const spiffeID = "spiffe://example.org/forwarder"
+
+type ca struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newCA(t *testing.T) *ca {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &ca{cert: cert, key: key}
+}
+
+// issue - writes a new certificate for spiffeID with the given serial number to certFile, and its key to keyFile
+func (c *ca) issue(t *testing.T, serial int64, certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	id, err := url.Parse(spiffeID)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		URIs:         []*url.URL{id},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, c.cert, &key.PublicKey, c.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	// Write the key first, so that both files match by the time the certificate is written
+	writePEM(t, keyFile, "PRIVATE KEY", keyDER)
+	writePEM(t, certFile, "CERTIFICATE", der)
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600))
+}
+
+func TestSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "fileidentity")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+	authority := newCA(t)
+	writePEM(t, caFile, "CERTIFICATE", authority.cert.Raw)
+	authority.issue(t, 2, certFile, keyFile)
+
+	source, err := fileidentity.New(certFile, keyFile, caFile)
+	require.NoError(t, err)
+	svid, err := source.GetX509SVID()
+	require.NoError(t, err)
+	require.Equal(t, spiffeID, svid.ID.String())
+	bundle, err := source.GetX509BundleForTrustDomain(spiffeid.RequireTrustDomainFromString("other.org"))
+	require.NoError(t, err)
+	require.True(t, bundle.HasX509Authority(authority.cert))
+
+	require.NoError(t, source.Watch(ctx))
+	authority.issue(t, 3, certFile, keyFile)
+	require.Eventually(t, func() bool {
+		svid, err = source.GetX509SVID()
+		return err == nil && svid.Certificates[0].SerialNumber.Int64() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// An invalid certificate keeps the previous one in use, until a valid one replaces it
+	require.NoError(t, ioutil.WriteFile(certFile, []byte("invalid"), 0600))
+	authority.issue(t, 4, certFile, keyFile)
+	require.Eventually(t, func() bool {
+		svid, err = source.GetX509SVID()
+		require.NoError(t, err)
+		require.NotNil(t, svid)
+		serial := svid.Certificates[0].SerialNumber.Int64()
+		require.Contains(t, []int64{3, 4}, serial)
+		return serial == 4
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNew_Invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileidentity")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	_, err = fileidentity.New(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt"))
+	require.Error(t, err)
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filewatch tells of the changes of files, such as those mounted from Kubernetes Secrets and ConfigMaps
+package filewatch
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/log"
+)
+
+// Watch - calls onChange whenever one of the files at paths may have changed, until ctx is done.  kind describes the
+//         files in errors and logs, e.g. "policy".
+func Watch(ctx context.Context, kind string, onChange func(), paths ...string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrapf(err, "failed to watch %s files", kind)
+	}
+	// Watch the directories rather than the files, which may be replaced rather than written to, as is the case
+	// with Kubernetes Secrets and ConfigMaps
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		if err = watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return errors.Wrapf(err, "failed to watch %s files in %s", kind, dir)
+		}
+	}
+	go func() {
+		defer func() { _ = watcher.Close() }()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case watchErr := <-watcher.Errors:
+				log.Entry(ctx).Warnf("error watching %s files: %+v", kind, watchErr)
+			case event := <-watcher.Events:
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				onChange()
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filewatch_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/filewatch"
+)
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "filewatch")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(path, []byte("1"), 0600))
+
+	var changes int32
+	require.NoError(t, filewatch.Watch(ctx, "test", func() { atomic.AddInt32(&changes, 1) }, path))
+
+	// Written to
+	require.NoError(t, ioutil.WriteFile(path, []byte("2"), 0600))
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&changes) > 0 }, 5*time.Second, 10*time.Millisecond)
+
+	// Replaced, as a Kubernetes Secret is
+	atomic.StoreInt32(&changes, 0)
+	replacement := filepath.Join(dir, "replacement")
+	require.NoError(t, ioutil.WriteFile(replacement, []byte("3"), 0600))
+	require.NoError(t, os.Rename(replacement, path))
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&changes) > 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestWatch_Missing(t *testing.T) {
+	require.Error(t, filewatch.Watch(context.Background(), "test", func() {}, filepath.Join(os.TempDir(), "missing", "file")))
+}
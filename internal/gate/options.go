@@ -0,0 +1,30 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gate
+
+import "github.com/networkservicemesh/api/pkg/api/networkservice"
+
+// Option - option for use with NewServer(...)
+type Option func(s *Server)
+
+// WithActiveFunc - sets the function telling whether a connection is active, so that its refreshes are still accepted
+// once the gate has been drained.  Without it, no Request is accepted once drained.
+func WithActiveFunc(isActive func(conn *networkservice.Connection) bool) Option {
+	return func(s *Server) {
+		s.isActive = isActive
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gate provides a NetworkServiceServer chain element that can be shut to stop accepting Requests, or drained
+// to only accept the refreshes of active connections, while still letting Closes through
+package gate
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/networkservicemesh/sdk/pkg/networkservice/core/next"
+)
+
+// Server - NetworkServiceServer chain element that rejects Requests once shut, and Requests for new connections
+// once drained
+type Server struct {
+	isActive func(conn *networkservice.Connection) bool
+	shut     int32
+	draining int32
+}
+
+// NewServer - returns a new Server with the gate open
+func NewServer(options ...Option) *Server {
+	s := &Server{
+		isActive: func(*networkservice.Connection) bool { return false },
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// Request - rejects the request with codes.Unavailable if the gate has been shut, or if it has been drained and the
+// request is not a refresh of an active connection.  Either way, the client may retry with another forwarder.
+func (s *Server) Request(ctx context.Context, request *networkservice.NetworkServiceRequest) (*networkservice.Connection, error) {
+	if atomic.LoadInt32(&s.shut) != 0 {
+		return nil, status.Error(codes.Unavailable, "forwarder is shutting down")
+	}
+	if atomic.LoadInt32(&s.draining) != 0 && !s.isActive(request.GetConnection()) {
+		return nil, status.Error(codes.Unavailable, "forwarder is draining")
+	}
+	return next.Server(ctx).Request(ctx, request)
+}
+
+// Close - always passes the Close on
+func (s *Server) Close(ctx context.Context, conn *networkservice.Connection) (*empty.Empty, error) {
+	return next.Server(ctx).Close(ctx, conn)
+}
+
+// Shut - shuts the gate, so that all subsequent Requests are rejected
+func (s *Server) Shut() {
+	atomic.StoreInt32(&s.shut, 1)
+}
+
+// Drain - drains the gate, so that all subsequent Requests are rejected, except for the refreshes of active
+// connections
+func (s *Server) Drain() {
+	atomic.StoreInt32(&s.draining, 1)
+}
+
+// Draining - returns whether the gate has been drained
+func (s *Server) Draining() bool {
+	return atomic.LoadInt32(&s.draining) != 0
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package healthcheck tracks the conditions the forwarder needs to serve, and reports them through the gRPC health
+// service and HTTP probes
+package healthcheck
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/log"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/httpserve"
+)
+
+const (
+	// LivePath - HTTP path of the liveness probe
+	LivePath = "/livez"
+	// ReadyPath - HTTP path of the readiness probe
+	ReadyPath = "/readyz"
+)
+
+// errNotChecked - the reason of a condition that has not been checked yet
+var errNotChecked = errors.New("not checked yet")
+
+// Health - the conditions the forwarder needs to serve.  Each of them is reported as a service of the gRPC health
+// server, and the services added with AddServices are only SERVING while all of them are met.
+type Health struct {
+	ctx    context.Context
+	server *health.Server
+
+	mu         sync.Mutex
+	conditions map[string]error
+	services   []string
+	serving    bool
+	shutdown   bool
+}
+
+// Status - the status of the forwarder, as served by the readiness probe
+type Status struct {
+	Serving    bool         `json:"serving"`
+	Conditions []*Condition `json:"conditions"`
+}
+
+// Condition - the status of one of the conditions the forwarder needs to serve
+type Condition struct {
+	Name    string `json:"name"`
+	Serving bool   `json:"serving"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// New - returns a Health reporting through server, initially without any condition
+func New(ctx context.Context, server *health.Server) *Health {
+	return &Health{
+		ctx:        ctx,
+		server:     server,
+		conditions: make(map[string]error),
+	}
+}
+
+// AddCondition - adds the condition reported as the name service, which is not met until Set otherwise
+func (h *Health) AddCondition(name string) {
+	h.Set(name, errNotChecked)
+}
+
+// Set - sets whether the name condition is met: it is if err is nil, otherwise err is the reason why it is not
+func (h *Health) Set(name string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.conditions[name] = err
+	h.server.SetServingStatus(name, servingStatus(err == nil))
+	h.update()
+}
+
+// AddServices - adds services that are only SERVING while all of the conditions are met
+func (h *Health) AddServices(services ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.services = append(h.services, services...)
+	for _, service := range services {
+		h.server.SetServingStatus(service, servingStatus(h.serving))
+	}
+}
+
+// Shutdown - reports every service as NOT_SERVING from now on, as the forwarder is shutting down
+func (h *Health) Shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.shutdown = true
+	h.server.Shutdown()
+	h.update()
+}
+
+// Status - returns the current status
+func (h *Health) Status() *Status {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := &Status{Serving: h.serving}
+	for name, err := range h.conditions {
+		condition := &Condition{Name: name, Serving: err == nil}
+		if err != nil {
+			condition.Reason = err.Error()
+		}
+		status.Conditions = append(status.Conditions, condition)
+	}
+	if h.shutdown {
+		status.Conditions = append(status.Conditions, &Condition{Name: "shutdown", Reason: "shutting down"})
+	}
+	sort.Slice(status.Conditions, func(i, j int) bool {
+		return status.Conditions[i].Name < status.Conditions[j].Name
+	})
+	return status
+}
+
+// Handler - returns an http.Handler serving the liveness probe on LivePath, and the readiness probe on ReadyPath.
+// The forwarder exits on the failures it cannot recover from, so it is live as long as it serves the liveness probe.
+// It is ready while all of the conditions are met, and the readiness probe lists them, along with the reasons of
+// those that are not.
+func (h *Health) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(LivePath, func(w http.ResponseWriter, r *http.Request) {
+		httpserve.WriteJSON(w, http.StatusOK, &Status{Serving: true})
+	})
+	mux.HandleFunc(ReadyPath, func(w http.ResponseWriter, r *http.Request) {
+		status := h.Status()
+		code := http.StatusOK
+		if !status.Serving {
+			code = http.StatusServiceUnavailable
+		}
+		httpserve.WriteJSON(w, code, status)
+	})
+	return mux
+}
+
+// update - updates the status of the services after a change of the conditions, logging whenever it changes.  Must be
+// called with h.mu locked.
+func (h *Health) update() {
+	var reasons []string
+	for name, err := range h.conditions {
+		if err != nil {
+			reasons = append(reasons, name+": "+err.Error())
+		}
+	}
+	sort.Strings(reasons)
+	serving := len(reasons) == 0 && !h.shutdown
+	if serving == h.serving {
+		return
+	}
+	h.serving = serving
+	for _, service := range h.services {
+		h.server.SetServingStatus(service, servingStatus(serving))
+	}
+	switch {
+	case serving:
+		log.Entry(h.ctx).Infof("health: SERVING")
+	case h.shutdown:
+		log.Entry(h.ctx).Infof("health: NOT_SERVING: shutting down")
+	default:
+		log.Entry(h.ctx).Warnf("health: NOT_SERVING: %s", strings.Join(reasons, "; "))
+	}
+}
+
+func servingStatus(serving bool) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	if serving {
+		return grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+}
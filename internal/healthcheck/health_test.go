@@ -0,0 +1,85 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/healthcheck"
+)
+
+func check(t *testing.T, server *health.Server, service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	resp, err := server.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	return resp.GetStatus()
+}
+
+func ready(t *testing.T, h *healthcheck.Health) (int, *healthcheck.Status) {
+	recorder := httptest.NewRecorder()
+	h.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, healthcheck.ReadyPath, nil))
+	status := &healthcheck.Status{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), status))
+	return recorder.Code, status
+}
+
+func TestHealth(t *testing.T) {
+	server := health.NewServer()
+	h := healthcheck.New(context.Background(), server)
+	h.AddCondition("vppagent")
+	h.AddCondition("registry")
+	h.AddServices("networkservice.NetworkService")
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, check(t, server, "networkservice.NetworkService"))
+
+	h.Set("vppagent", nil)
+	h.Set("registry", errors.New("registry unreachable"))
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, check(t, server, "vppagent"))
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, check(t, server, "registry"))
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, check(t, server, "networkservice.NetworkService"))
+	code, status := ready(t, h)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, &healthcheck.Status{
+		Serving: false,
+		Conditions: []*healthcheck.Condition{
+			{Name: "registry", Reason: "registry unreachable"},
+			{Name: "vppagent", Serving: true},
+		},
+	}, status)
+
+	h.Set("registry", nil)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, check(t, server, "networkservice.NetworkService"))
+	code, status = ready(t, h)
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, status.Serving)
+
+	h.Shutdown()
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, check(t, server, "networkservice.NetworkService"))
+	code, _ = ready(t, h)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+
+	recorder := httptest.NewRecorder()
+	h.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, healthcheck.LivePath, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
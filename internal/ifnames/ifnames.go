@@ -0,0 +1,77 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ifnames provides the names sdk-vppagent gives the interfaces it creates for a connection
+package ifnames
+
+import (
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/kernel"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/srv6"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/vxlan"
+)
+
+const (
+	// ServerPrefix - prefix of the names of the interfaces of the server (incoming) side of a connection, followed
+	// by the id of the connection
+	ServerPrefix = "server-"
+	// ClientPrefix - prefix of the names of the interfaces of the client (outgoing) side of a connection, followed
+	// by the id of the connection
+	ClientPrefix = "client-"
+	// VethSuffix - suffix of the name of the forwarder's end of a veth pair
+	VethSuffix = "-veth"
+	// MemifSocketSuffix - suffix of the name of the socket file of a memif interface of the server side of a
+	// connection, preceded by the id of the connection
+	MemifSocketSuffix = ".memif.socket"
+)
+
+// Prefixes - the prefixes of the names of the interfaces of either side of a connection
+var Prefixes = []string{ServerPrefix, ClientPrefix}
+
+// Server - returns the name of the VPP interface of the server side of the connection with id using mechanism
+func Server(mechanism *networkservice.Mechanism, id string) string {
+	return name(ServerPrefix, mechanism, id)
+}
+
+// Client - returns the name of the VPP interface of the client side of the connection with id using mechanism
+func Client(mechanism *networkservice.Mechanism, id string) string {
+	return name(ClientPrefix, mechanism, id)
+}
+
+// IsTunnel - returns whether the interfaces of mechanism are tunnels, which are named after their connection id alone
+func IsTunnel(mechanism *networkservice.Mechanism) bool {
+	switch mechanism.GetType() {
+	case vxlan.MECHANISM, srv6.MECHANISM:
+		return true
+	default:
+		return false
+	}
+}
+
+// HostIfName - returns name truncated to the maximum length of a Linux interface name, as sdk-vppagent does
+func HostIfName(name string) string {
+	if len(name) <= kernel.LinuxIfMaxLength {
+		return name
+	}
+	return name[:kernel.LinuxIfMaxLength]
+}
+
+func name(prefix string, mechanism *networkservice.Mechanism, id string) string {
+	if IsTunnel(mechanism) {
+		return id
+	}
+	return prefix + id
+}
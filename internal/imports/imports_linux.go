@@ -5,16 +5,33 @@ import (
 	_ "bufio"
 	_ "bytes"
 	_ "context"
+	_ "crypto/ecdsa"
+	_ "crypto/elliptic"
+	_ "crypto/rand"
+	_ "crypto/tls"
+	_ "crypto/x509"
+	_ "encoding"
+	_ "encoding/json"
+	_ "encoding/pem"
+	_ "flag"
 	_ "fmt"
 	_ "github.com/antonfisher/nested-logrus-formatter"
+	_ "github.com/dgrijalva/jwt-go"
 	_ "github.com/edwarnicke/exechelper"
 	_ "github.com/edwarnicke/grpcfd"
+	_ "github.com/fsnotify/fsnotify"
+	_ "github.com/golang/protobuf/jsonpb"
+	_ "github.com/golang/protobuf/proto"
 	_ "github.com/golang/protobuf/ptypes"
 	_ "github.com/golang/protobuf/ptypes/empty"
 	_ "github.com/kelseyhightower/envconfig"
+	_ "github.com/networkservicemesh/api/pkg/api"
 	_ "github.com/networkservicemesh/api/pkg/api/networkservice"
-	_ "github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/cls"
+	_ "github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/common"
 	_ "github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/kernel"
+	_ "github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/memif"
+	_ "github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/srv6"
+	_ "github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/vxlan"
 	_ "github.com/networkservicemesh/api/pkg/api/registry"
 	_ "github.com/networkservicemesh/sdk-vppagent/pkg/networkservice/chains/xconnectns"
 	_ "github.com/networkservicemesh/sdk-vppagent/pkg/networkservice/commit"
@@ -39,43 +56,83 @@ import (
 	_ "github.com/networkservicemesh/sdk/pkg/registry/common/setid"
 	_ "github.com/networkservicemesh/sdk/pkg/registry/core/adapters"
 	_ "github.com/networkservicemesh/sdk/pkg/registry/core/chain"
+	_ "github.com/networkservicemesh/sdk/pkg/registry/core/streamchannel"
 	_ "github.com/networkservicemesh/sdk/pkg/registry/memory"
 	_ "github.com/networkservicemesh/sdk/pkg/tools/debug"
 	_ "github.com/networkservicemesh/sdk/pkg/tools/grpcutils"
 	_ "github.com/networkservicemesh/sdk/pkg/tools/log"
 	_ "github.com/networkservicemesh/sdk/pkg/tools/signalctx"
+	_ "github.com/networkservicemesh/sdk/pkg/tools/spanhelper"
 	_ "github.com/networkservicemesh/sdk/pkg/tools/spiffejwt"
 	_ "github.com/networkservicemesh/sdk/pkg/tools/spire"
+	_ "github.com/open-policy-agent/opa/ast"
+	_ "github.com/open-policy-agent/opa/rego"
+	_ "github.com/opentracing/opentracing-go"
+	_ "github.com/opentracing/opentracing-go/mocktracer"
 	_ "github.com/phayes/freeport"
 	_ "github.com/pkg/errors"
+	_ "github.com/prometheus/client_golang/prometheus"
+	_ "github.com/prometheus/client_golang/prometheus/promhttp"
 	_ "github.com/sirupsen/logrus"
 	_ "github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	_ "github.com/spiffe/go-spiffe/v2/spiffeid"
 	_ "github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
 	_ "github.com/spiffe/go-spiffe/v2/svid/x509svid"
 	_ "github.com/spiffe/go-spiffe/v2/workloadapi"
 	_ "github.com/stretchr/testify/require"
 	_ "github.com/stretchr/testify/suite"
+	_ "github.com/uber/jaeger-client-go"
+	_ "github.com/uber/jaeger-client-go/config"
 	_ "github.com/vishvananda/netlink"
 	_ "github.com/vishvananda/netns"
 	_ "go.ligato.io/vpp-agent/v3/proto/ligato/configurator"
+	_ "go.ligato.io/vpp-agent/v3/proto/ligato/linux"
+	_ "go.ligato.io/vpp-agent/v3/proto/ligato/linux/interfaces"
 	_ "go.ligato.io/vpp-agent/v3/proto/ligato/vpp"
 	_ "go.ligato.io/vpp-agent/v3/proto/ligato/vpp/acl"
 	_ "go.ligato.io/vpp-agent/v3/proto/ligato/vpp/interfaces"
+	_ "go.ligato.io/vpp-agent/v3/proto/ligato/vpp/l2"
 	_ "go.ligato.io/vpp-agent/v3/proto/ligato/vpp/l3"
-	_ "golang.org/x/sys/unix"
+	_ "go.opentelemetry.io/otel"
+	_ "go.opentelemetry.io/otel/bridge/opentracing"
+	_ "go.opentelemetry.io/otel/exporters/otlp"
+	_ "go.opentelemetry.io/otel/propagation"
+	_ "go.opentelemetry.io/otel/sdk/resource"
+	_ "go.opentelemetry.io/otel/sdk/trace"
+	_ "go.opentelemetry.io/otel/semconv"
 	_ "google.golang.org/grpc"
+	_ "google.golang.org/grpc/backoff"
+	_ "google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/connectivity"
 	_ "google.golang.org/grpc/credentials"
+	_ "google.golang.org/grpc/health"
 	_ "google.golang.org/grpc/health/grpc_health_v1"
+	_ "google.golang.org/grpc/metadata"
+	_ "google.golang.org/grpc/peer"
+	_ "google.golang.org/grpc/resolver"
+	_ "google.golang.org/grpc/status"
+	_ "gopkg.in/yaml.v2"
 	_ "io"
 	_ "io/ioutil"
+	_ "math"
+	_ "math/big"
 	_ "net"
+	_ "net/http"
+	_ "net/http/httptest"
 	_ "net/url"
 	_ "os"
+	_ "os/signal"
 	_ "path/filepath"
+	_ "reflect"
 	_ "regexp"
+	_ "sort"
 	_ "strconv"
 	_ "strings"
+	_ "sync"
+	_ "sync/atomic"
 	_ "syscall"
 	_ "testing"
+	_ "text/tabwriter"
 	_ "time"
+	_ "unicode"
 )
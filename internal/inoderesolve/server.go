@@ -0,0 +1,111 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !windows
+
+// Package inoderesolve provides a NetworkServiceServer chain element that resolves the inode URL of the network
+// namespace of a kernel mechanism for Requests that did not arrive over a connection able to pass its file
+// descriptor, such as connections being replayed or restored by the forwarder itself
+package inoderesolve
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strconv"
+
+	"github.com/edwarnicke/grpcfd"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/common"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/kernel"
+	"github.com/pkg/errors"
+
+	"github.com/networkservicemesh/sdk/pkg/networkservice/core/next"
+)
+
+const (
+	inodeScheme = "inode"
+	procDir     = "/proc"
+)
+
+type inodeResolveServer struct{}
+
+// NewServer - returns a NetworkServiceServer chain element that, if the Request cannot receive file descriptors,
+// swaps the inode URL of the network namespace of a kernel mechanism for a file URL of the namespace of a process
+// in that namespace, and swaps it back on the returned connection.  It must be placed before recvfd.
+func NewServer() networkservice.NetworkServiceServer {
+	return &inodeResolveServer{}
+}
+
+func (s *inodeResolveServer) Request(ctx context.Context, request *networkservice.NetworkServiceRequest) (*networkservice.Connection, error) {
+	if _, ok := grpcfd.FromContext(ctx); ok {
+		return next.Server(ctx).Request(ctx, request)
+	}
+	mechanism := kernel.ToMechanism(request.GetConnection().GetMechanism())
+	if mechanism == nil {
+		return next.Server(ctx).Request(ctx, request)
+	}
+	inodeURL := mechanism.GetNetNSURL()
+	fileURL, err := resolve(inodeURL)
+	if err != nil {
+		return nil, err
+	}
+	if fileURL == inodeURL {
+		return next.Server(ctx).Request(ctx, request)
+	}
+	mechanism.SetNetNSURL(fileURL)
+	conn, err := next.Server(ctx).Request(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if params := conn.GetMechanism().GetParameters(); params != nil && params[common.InodeURL] == fileURL {
+		params[common.InodeURL] = inodeURL
+	}
+	return conn, nil
+}
+
+func (s *inodeResolveServer) Close(ctx context.Context, conn *networkservice.Connection) (*empty.Empty, error) {
+	return next.Server(ctx).Close(ctx, conn)
+}
+
+// resolve - returns a file URL of the network namespace of some process whose inode is given by urlStr, or urlStr
+// itself if it is not an inode URL
+func resolve(urlStr string) (string, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	if u.Scheme != inodeScheme {
+		return urlStr, nil
+	}
+	infos, err := ioutil.ReadDir(procDir)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	for _, info := range infos {
+		if _, convErr := strconv.Atoi(info.Name()); convErr != nil || !info.IsDir() {
+			continue
+		}
+		filename := filepath.Join(procDir, info.Name(), "ns", "net")
+		// Processes may exit at any time, so failing to stat one is not an error
+		if nsURL, statErr := grpcfd.FilenameToURL(filename); statErr == nil && nsURL.String() == u.String() {
+			return (&url.URL{Scheme: kernel.NetNSURLScheme, Path: filename}).String(), nil
+		}
+	}
+	return "", errors.Errorf("no process found in network namespace %s", urlStr)
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package listen serves the forwarder's grpc server on unix sockets and TCP addresses
+package listen
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/grpcutils"
+)
+
+const (
+	unixScheme = "unix"
+	tcpScheme  = "tcp"
+	// staleDialTimeout - how long to try connecting to an existing socket to tell whether it is still in use
+	staleDialTimeout = time.Second
+)
+
+// Serve - serves server on each of urls until ctx is done, updating each url with the address actually listened on,
+//         such as the port chosen for tcp://:0.  A stale socket left at a unix url by a previous process is removed
+//         first.  The returned channel receives the errors of the listeners, and is closed once all of them have
+//         stopped.
+func Serve(ctx context.Context, urls []*url.URL, server *grpc.Server) <-chan error {
+	errCh := make(chan error, len(urls))
+	var wg sync.WaitGroup
+	for _, u := range urls {
+		if u.Scheme == unixScheme {
+			if err := RemoveStaleSocket(u.Path); err != nil {
+				errCh <- err
+				continue
+			}
+		}
+		wg.Add(1)
+		go func(listenErrCh <-chan error) {
+			defer wg.Done()
+			for err := range listenErrCh {
+				errCh <- err
+			}
+		}(grpcutils.ListenAndServe(ctx, u, server))
+	}
+	go func() {
+		wg.Wait()
+		close(errCh)
+	}()
+	return errCh
+}
+
+// RemoveStaleSocket - removes the unix socket at path, unless some process still accepts connections on it.  It is an
+//                     error for path to be anything else than a socket.
+func RemoveStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return errors.Errorf("cannot listen on %s: it exists and is not a socket", path)
+	}
+	if conn, dialErr := net.DialTimeout(unixScheme, path, staleDialTimeout); dialErr == nil {
+		_ = conn.Close()
+		return errors.Errorf("cannot listen on %s: another process is listening on it", path)
+	}
+	return errors.Wrapf(os.Remove(path), "failed to remove stale socket %s", path)
+}
+
+// AdvertisedURL - returns the url for others to reach the forwarder listening on u: u itself, unless it is a TCP
+//                 address with an unspecified IP, which is replaced by ip
+func AdvertisedURL(u *url.URL, ip net.IP) *url.URL {
+	if u.Scheme != tcpScheme {
+		return u
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil || (host != "" && !net.ParseIP(host).IsUnspecified()) || ip == nil {
+		return u
+	}
+	return &url.URL{Scheme: tcpScheme, Host: net.JoinHostPort(ip.String(), port)}
+}
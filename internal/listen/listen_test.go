@@ -0,0 +1,125 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package listen_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/listen"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "listen")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	dir := tempDir(t)
+	path := filepath.Join(dir, "stale.sock")
+
+	require.NoError(t, listen.RemoveStaleSocket(path))
+
+	// A socket nobody listens on any more
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, ln.Close())
+	require.NoError(t, listen.RemoveStaleSocket(path))
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+
+	// A socket still in use
+	ln, err = net.Listen("unix", path)
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+	require.Error(t, listen.RemoveStaleSocket(path))
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+
+	// Not a socket
+	file := filepath.Join(dir, "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0600))
+	require.Error(t, listen.RemoveStaleSocket(file))
+}
+
+func TestServe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := grpc.NewServer()
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	urls := []*url.URL{
+		{Scheme: "unix", Path: filepath.Join(tempDir(t), "listen.on")},
+		{Scheme: "tcp", Host: "127.0.0.1:0"},
+	}
+	errCh := listen.Serve(ctx, urls, server)
+	require.NotEqual(t, "127.0.0.1:0", urls[1].Host)
+
+	for _, target := range []string{urls[0].String(), urls[1].Host} {
+		dialCtx, dialCancel := context.WithTimeout(ctx, 5*time.Second)
+		cc, err := grpc.DialContext(dialCtx, target, grpc.WithInsecure(), grpc.WithBlock())
+		dialCancel()
+		require.NoError(t, err)
+		_, err = grpc_health_v1.NewHealthClient(cc).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+		require.NoError(t, err)
+		_ = cc.Close()
+	}
+
+	cancel()
+	for err := range errCh {
+		require.NoError(t, err)
+	}
+}
+
+func TestServe_InUse(t *testing.T) {
+	path := filepath.Join(tempDir(t), "listen.on")
+	ln, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	errCh := listen.Serve(context.Background(), []*url.URL{{Scheme: "unix", Path: path}}, grpc.NewServer())
+	require.Error(t, <-errCh)
+}
+
+func TestAdvertisedURL(t *testing.T) {
+	ip := net.ParseIP("10.0.0.1")
+	for listenOn, expected := range map[string]string{
+		"unix:///var/lib/forwarder/listen.on": "unix:///var/lib/forwarder/listen.on",
+		"tcp://:5001":                         "tcp://10.0.0.1:5001",
+		"tcp://0.0.0.0:5001":                  "tcp://10.0.0.1:5001",
+		"tcp://[::]:5001":                     "tcp://10.0.0.1:5001",
+		"tcp://192.168.0.1:5001":              "tcp://192.168.0.1:5001",
+	} {
+		u, err := url.Parse(listenOn)
+		require.NoError(t, err)
+		require.Equal(t, expected, listen.AdvertisedURL(u, ip).String(), listenOn)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !windows
+
+// Package logging configures the level and format of the logrus standard logger, and lets the level be changed
+// while the forwarder is running
+package logging
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	nested "github.com/antonfisher/nested-logrus-formatter"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/log"
+)
+
+const (
+	// FormatText - human readable text log format
+	FormatText = "text"
+	// FormatJSON - one JSON object per log line
+	FormatJSON = "json"
+)
+
+// Configure - sets the level and format of the logrus standard logger
+func Configure(level, format string) error {
+	switch strings.ToLower(format) {
+	case FormatText:
+		logrus.SetFormatter(&nested.Formatter{})
+	case FormatJSON:
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return errors.Errorf("unknown log format %q, must be %q or %q", format, FormatText, FormatJSON)
+	}
+	return SetLevel(level)
+}
+
+// SetLevel - sets the level of the logrus standard logger
+func SetLevel(level string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	logrus.SetLevel(lvl)
+	return nil
+}
+
+// HandleSignals - until ctx is done, makes the log level one step more verbose on each SIGUSR1 and one step less
+// verbose on each SIGUSR2
+func HandleSignals(ctx context.Context) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(sigCh)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-sigCh:
+				level := logrus.GetLevel()
+				switch {
+				case sig == syscall.SIGUSR1 && level < logrus.TraceLevel:
+					level++
+				case sig == syscall.SIGUSR2 && level > logrus.PanicLevel:
+					level--
+				}
+				logrus.SetLevel(level)
+				log.Entry(ctx).Warnf("caught signal %s, log level is now %s", sig, level)
+			}
+		}
+	}()
+}
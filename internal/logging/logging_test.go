@@ -0,0 +1,81 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !windows
+
+package logging_test
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/logging"
+)
+
+func TestConfigure(t *testing.T) {
+	defer logrus.SetLevel(logrus.GetLevel())
+
+	require.NoError(t, logging.Configure("debug", logging.FormatJSON))
+	require.Equal(t, logrus.DebugLevel, logrus.GetLevel())
+	require.NoError(t, logging.Configure("WARN", logging.FormatText))
+	require.Equal(t, logrus.WarnLevel, logrus.GetLevel())
+
+	require.Error(t, logging.Configure("loud", logging.FormatText))
+	require.Error(t, logging.Configure("info", "xml"))
+}
+
+func TestHandleSignals(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer logrus.SetLevel(logrus.GetLevel())
+	hook := test.NewLocal(logrus.StandardLogger())
+
+	logrus.SetLevel(logrus.InfoLevel)
+	logging.HandleSignals(ctx)
+	signal := func(sig syscall.Signal, level logrus.Level) {
+		require.NoError(t, syscall.Kill(os.Getpid(), sig))
+		require.Eventually(t, func() bool { return logrus.GetLevel() == level }, time.Second, 10*time.Millisecond)
+	}
+
+	signal(syscall.SIGUSR1, logrus.DebugLevel)
+	signal(syscall.SIGUSR1, logrus.TraceLevel)
+
+	// Trace is as verbose as it gets, which the signal caught still tells
+	hook.Reset()
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	require.Eventually(t, func() bool {
+		entry := hook.LastEntry()
+		return entry != nil && entry.Message == "caught signal user defined signal 1, log level is now trace"
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, logrus.TraceLevel, logrus.GetLevel())
+
+	for _, level := range []logrus.Level{logrus.DebugLevel, logrus.InfoLevel, logrus.WarnLevel, logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel} {
+		signal(syscall.SIGUSR2, level)
+	}
+
+	// Panic is as quiet as it gets, so one step more verbose is Fatal.  Nothing is logged at Panic, hence the sleep for
+	// the signal to be handled before the next one.
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+	time.Sleep(100 * time.Millisecond)
+	signal(syscall.SIGUSR1, logrus.FatalLevel)
+}
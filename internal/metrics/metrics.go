@@ -0,0 +1,173 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics collects the Prometheus metrics of the forwarder and serves them over HTTP
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// Namespace - prefix of the names of the forwarder's metrics
+	Namespace = "forwarder"
+	// Path - HTTP path the metrics are served on
+	Path = "/metrics"
+)
+
+// Metrics - the Prometheus metrics of the forwarder
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests         *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	closes           *prometheus.CounterVec
+	closeDuration    *prometheus.HistogramVec
+	phaseDuration    *prometheus.GaugeVec
+	registered       prometheus.Gauge
+	vppagentFailures *prometheus.CounterVec
+	rejectedPeers    *prometheus.CounterVec
+	rejectedRequests *prometheus.CounterVec
+
+	mu         sync.Mutex
+	phase      string
+	phaseStart time.Time
+}
+
+// New - returns new Metrics, registered along with the Go runtime and process metrics
+func New() *Metrics {
+	mechanismLabels := []string{"local_mechanism", "remote_mechanism"}
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "requests_total",
+			Help:      "Number of Requests handled, by mechanism pair and grpc status code",
+		}, append(mechanismLabels, "code")),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Time taken to handle Requests, by mechanism pair",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+		}, mechanismLabels),
+		closes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "closes_total",
+			Help:      "Number of Closes handled, by mechanism pair and grpc status code",
+		}, append(mechanismLabels, "code")),
+		closeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "close_duration_seconds",
+			Help:      "Time taken to handle Closes, by mechanism pair",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+		}, mechanismLabels),
+		phaseDuration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "startup_phase_duration_seconds",
+			Help:      "Time taken by each phase of the startup",
+		}, []string{"phase"}),
+		registered: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Name:      "registered",
+			Help:      "Whether the last attempt to register with the registry succeeded (1) or not (0)",
+		}),
+		vppagentFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "vppagent_transaction_failures_total",
+			Help:      "Number of failed vppagent configuration transactions, by operation",
+		}, []string{"operation"}),
+		rejectedPeers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "rejected_peers_total",
+			Help:      "Number of mTLS peers rejected for their SPIFFE ID, by direction (incoming or outgoing)",
+		}, []string{"direction"}),
+		rejectedRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "rejected_requests_total",
+			Help:      "Number of Requests rejected for exceeding the forwarder's limits, by reason",
+		}, []string{"reason"}),
+	}
+	m.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.closes,
+		m.closeDuration,
+		m.phaseDuration,
+		m.registered,
+		m.vppagentFailures,
+		m.rejectedPeers,
+		m.rejectedRequests,
+	)
+	return m
+}
+
+// Register - registers additional collectors along with the metrics of m
+func (m *Metrics) Register(collectors ...prometheus.Collector) {
+	m.registry.MustRegister(collectors...)
+}
+
+// SetActiveConnectionsFunc - makes the number of active connections reported be the value returned by f
+func (m *Metrics) SetActiveConnectionsFunc(f func() int) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "active_connections",
+		Help:      "Number of active connections",
+	}, func() float64 { return float64(f()) }))
+}
+
+// StartPhase - records the duration of the current startup phase, if any, and starts timing phase, unless it is ""
+//              for the startup having ended, as startup.Sequence.End() does
+func (m *Metrics) StartPhase(phase string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if m.phase != "" {
+		m.phaseDuration.WithLabelValues(m.phase).Set(now.Sub(m.phaseStart).Seconds())
+	}
+	m.phase = phase
+	m.phaseStart = now
+}
+
+// SetRegistered - records whether the last attempt to register with the registry succeeded
+func (m *Metrics) SetRegistered(registered bool) {
+	if registered {
+		m.registered.Set(1)
+		return
+	}
+	m.registered.Set(0)
+}
+
+// PeerRejected - records that an incoming or outgoing mTLS peer was rejected for its SPIFFE ID
+func (m *Metrics) PeerRejected(direction string) {
+	m.rejectedPeers.WithLabelValues(direction).Inc()
+}
+
+// RequestRejected - records that a Request was rejected for exceeding the forwarder's limits, for reason
+func (m *Metrics) RequestRejected(reason string) {
+	m.rejectedRequests.WithLabelValues(reason).Inc()
+}
+
+// Handler - returns an http.Handler serving the metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/kernel"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/vxlan"
+	"github.com/stretchr/testify/require"
+
+	"github.com/networkservicemesh/sdk/pkg/networkservice/core/chain"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/metrics"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", metrics.Path, nil))
+	body, err := ioutil.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetrics_Server(t *testing.T) {
+	m := metrics.New()
+	outgoing := func(conn *networkservice.Connection) (*networkservice.Connection, bool) {
+		return &networkservice.Connection{Mechanism: &networkservice.Mechanism{Type: vxlan.MECHANISM}}, true
+	}
+	server := chain.NewNetworkServiceServer(m.NewServer(outgoing))
+	conn := &networkservice.Connection{
+		Id:        "id",
+		Mechanism: &networkservice.Mechanism{Type: kernel.MECHANISM},
+	}
+
+	_, err := server.Request(context.Background(), &networkservice.NetworkServiceRequest{Connection: conn})
+	require.NoError(t, err)
+	_, err = server.Close(context.Background(), conn)
+	require.NoError(t, err)
+
+	body := scrape(t, m)
+	require.Contains(t, body, `forwarder_requests_total{code="OK",local_mechanism="KERNEL_INTERFACE",remote_mechanism="VXLAN"} 1`)
+	require.Contains(t, body, `forwarder_closes_total{code="OK",local_mechanism="KERNEL_INTERFACE",remote_mechanism="VXLAN"} 1`)
+	require.Contains(t, body, `forwarder_request_duration_seconds_count{local_mechanism="KERNEL_INTERFACE",remote_mechanism="VXLAN"} 1`)
+}
+
+func TestMetrics_Status(t *testing.T) {
+	m := metrics.New()
+	m.SetActiveConnectionsFunc(func() int { return 3 })
+	m.SetRegistered(true)
+	m.RequestRejected("connections")
+	m.StartPhase("config")
+	m.StartPhase("")
+
+	body := scrape(t, m)
+	require.Contains(t, body, "forwarder_active_connections 3")
+	require.Contains(t, body, "forwarder_registered 1")
+	require.Contains(t, body, `forwarder_rejected_requests_total{reason="connections"} 1`)
+	require.Contains(t, body, `forwarder_startup_phase_duration_seconds{phase="config"}`)
+}
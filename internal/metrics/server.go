@@ -0,0 +1,82 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"google.golang.org/grpc/status"
+
+	"github.com/networkservicemesh/sdk/pkg/networkservice/core/next"
+)
+
+const unknownMechanism = "unknown"
+
+// OutgoingFunc - returns the outgoing connection corresponding to the incoming connection conn, if known
+type OutgoingFunc func(conn *networkservice.Connection) (*networkservice.Connection, bool)
+
+type metricsServer struct {
+	metrics  *Metrics
+	outgoing OutgoingFunc
+}
+
+// NewServer - returns a NetworkServiceServer chain element recording the count, duration and status code of
+// Requests and Closes in m, labelled with the mechanisms of the incoming connection and of the outgoing connection
+// looked up by outgoing.  It should be placed first in the chain, so as to include everything the chain does.
+func (m *Metrics) NewServer(outgoing OutgoingFunc) networkservice.NetworkServiceServer {
+	return &metricsServer{
+		metrics:  m,
+		outgoing: outgoing,
+	}
+}
+
+func (s *metricsServer) Request(ctx context.Context, request *networkservice.NetworkServiceRequest) (*networkservice.Connection, error) {
+	start := time.Now()
+	conn, err := next.Server(ctx).Request(ctx, request)
+	labelled := conn
+	if labelled == nil {
+		labelled = request.GetConnection()
+	}
+	local, remote := s.mechanisms(labelled)
+	s.metrics.requestDuration.WithLabelValues(local, remote).Observe(time.Since(start).Seconds())
+	s.metrics.requests.WithLabelValues(local, remote, status.Code(err).String()).Inc()
+	return conn, err
+}
+
+func (s *metricsServer) Close(ctx context.Context, conn *networkservice.Connection) (*empty.Empty, error) {
+	// Look the mechanisms up beforehand, as the outgoing connection is forgotten once closed
+	local, remote := s.mechanisms(conn)
+	start := time.Now()
+	rv, err := next.Server(ctx).Close(ctx, conn)
+	s.metrics.closeDuration.WithLabelValues(local, remote).Observe(time.Since(start).Seconds())
+	s.metrics.closes.WithLabelValues(local, remote, status.Code(err).String()).Inc()
+	return rv, err
+}
+
+func (s *metricsServer) mechanisms(conn *networkservice.Connection) (local, remote string) {
+	local, remote = unknownMechanism, unknownMechanism
+	if mechanism := conn.GetMechanism(); mechanism != nil {
+		local = mechanism.GetType()
+	}
+	if outgoing, ok := s.outgoing(conn); ok && outgoing.GetMechanism() != nil {
+		remote = outgoing.GetMechanism().GetType()
+	}
+	return local, remote
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// configuratorService - prefix of the full names of the methods of the vppagent configurator
+const configuratorService = "/ligato.configurator.ConfiguratorService/"
+
+type vppagentClientConn struct {
+	grpc.ClientConnInterface
+	metrics *Metrics
+}
+
+// WrapVppagent - returns a grpc.ClientConnInterface to the vppagent reached by cc that counts the failed
+// configuration transactions in m
+func (m *Metrics) WrapVppagent(cc grpc.ClientConnInterface) grpc.ClientConnInterface {
+	return &vppagentClientConn{
+		ClientConnInterface: cc,
+		metrics:             m,
+	}
+}
+
+func (c *vppagentClientConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	err := c.ClientConnInterface.Invoke(ctx, method, args, reply, opts...)
+	if err != nil && strings.HasPrefix(method, configuratorService) {
+		operation := strings.ToLower(strings.TrimPrefix(method, configuratorService))
+		if operation == "update" || operation == "delete" {
+			c.metrics.vppagentFailures.WithLabelValues(operation).Inc()
+		}
+	}
+	return err
+}
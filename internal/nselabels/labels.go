@@ -0,0 +1,146 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nselabels provides the labels the forwarder advertises with its NetworkServiceEndpoint registration, so
+// that NSMgr and forwarder selection can choose between forwarders by their location and capabilities
+package nselabels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/kernel"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/memif"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/srv6"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/vxlan"
+	"github.com/pkg/errors"
+)
+
+const (
+	// NodeNameKey - label of the name of the node the forwarder runs on
+	NodeNameKey = "nodeName"
+	// TunnelIPKey - label of the IP the forwarder originates and terminates tunnels on
+	TunnelIPKey = "tunnelIP"
+	// LocalMechanismsKey - label of the comma separated mechanism types supported for connections on the node
+	LocalMechanismsKey = "localMechanisms"
+	// RemoteMechanismsKey - label of the comma separated mechanism types supported for connections between nodes
+	RemoteMechanismsKey = "remoteMechanisms"
+	// VppVersionKey - label of the version of VPP
+	VppVersionKey = "vppVersion"
+	// ForwarderVersionKey - label of the version of the forwarder
+	ForwarderVersionKey = "forwarderVersion"
+	// CapacityKey - label of the maximum number of connections the forwarder takes
+	CapacityKey = "capacity"
+
+	// vppCommandPath - path of the vppagent REST API running VPP CLI commands
+	vppCommandPath = "/vpp/command"
+)
+
+var (
+	// LocalMechanisms - the mechanism types xconnectns supports for connections on the node
+	LocalMechanisms = []string{kernel.MECHANISM, memif.MECHANISM}
+	// RemoteMechanisms - the mechanism types xconnectns supports for connections between nodes
+	RemoteMechanisms = []string{vxlan.MECHANISM, srv6.MECHANISM}
+)
+
+// Labels - what the forwarder advertises about itself.  Unset fields are left out of the labels.
+type Labels struct {
+	NodeName         string
+	TunnelIP         net.IP
+	LocalMechanisms  []string
+	RemoteMechanisms []string
+	VppVersion       string
+	ForwarderVersion string
+	// Capacity - 0 if the number of connections is not limited
+	Capacity int
+	// Extra - operator supplied labels, which take precedence over the others
+	Extra map[string]string
+}
+
+// Map - returns the labels as a map for use in a registry.NetworkServiceLabels
+func (l *Labels) Map() map[string]string {
+	rv := make(map[string]string)
+	set := func(key, value string) {
+		if value != "" {
+			rv[key] = value
+		}
+	}
+	set(NodeNameKey, l.NodeName)
+	if l.TunnelIP != nil {
+		set(TunnelIPKey, l.TunnelIP.String())
+	}
+	set(LocalMechanismsKey, strings.Join(l.LocalMechanisms, ","))
+	set(RemoteMechanismsKey, strings.Join(l.RemoteMechanisms, ","))
+	set(VppVersionKey, l.VppVersion)
+	set(ForwarderVersionKey, l.ForwarderVersion)
+	if l.Capacity > 0 {
+		set(CapacityKey, strconv.Itoa(l.Capacity))
+	}
+	for key, value := range l.Extra {
+		rv[key] = value
+	}
+	return rv
+}
+
+// String - returns the labels as a sorted, comma separated list of key=value pairs, for logging
+func (l *Labels) String() string {
+	m := l.Map()
+	pairs := make([]string, 0, len(m))
+	for key, value := range m {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// VppVersion - returns the version of VPP, as reported by "show version" through the REST API of the vppagent at
+//              baseURL, such as http://localhost:9191
+func VppVersion(ctx context.Context, client *http.Client, baseURL string) (string, error) {
+	body, err := json.Marshal(map[string]string{"vppclicommand": "show version"})
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+vppCommandPath, bytes.NewReader(body))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := client.Do(request)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to reach the vppagent REST API")
+	}
+	defer func() { _ = response.Body.Close() }()
+	if response.StatusCode != http.StatusOK {
+		return "", errors.Errorf("POST %s: %s", vppCommandPath, response.Status)
+	}
+	// The output of the command is returned as a JSON string: "vpp v20.05.1-release built by root on ..."
+	var output string
+	if err = json.NewDecoder(response.Body).Decode(&output); err != nil {
+		return "", errors.Wrap(err, "failed to decode the output of show version")
+	}
+	fields := strings.Fields(output)
+	if len(fields) < 2 || fields[0] != "vpp" {
+		return "", errors.Errorf("unexpected output of show version: %q", output)
+	}
+	return fields[1], nil
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nselabels_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/nselabels"
+)
+
+func TestLabels_Map(t *testing.T) {
+	labels := &nselabels.Labels{
+		NodeName:         "node-1",
+		TunnelIP:         net.ParseIP("10.0.0.1"),
+		LocalMechanisms:  nselabels.LocalMechanisms,
+		RemoteMechanisms: nselabels.RemoteMechanisms,
+		ForwarderVersion: "v1.0.0",
+		Capacity:         100,
+		Extra:            map[string]string{"zone": "a", nselabels.NodeNameKey: "override"},
+	}
+	require.Equal(t, map[string]string{
+		nselabels.NodeNameKey:         "override",
+		nselabels.TunnelIPKey:         "10.0.0.1",
+		nselabels.LocalMechanismsKey:  "KERNEL_INTERFACE,MEMIF",
+		nselabels.RemoteMechanismsKey: "VXLAN,SRV6",
+		nselabels.ForwarderVersionKey: "v1.0.0",
+		nselabels.CapacityKey:         "100",
+		"zone":                        "a",
+	}, labels.Map())
+}
+
+func TestLabels_MapOmitsUnset(t *testing.T) {
+	labels := &nselabels.Labels{NodeName: "node-1"}
+	require.Equal(t, map[string]string{nselabels.NodeNameKey: "node-1"}, labels.Map())
+	require.Equal(t, "nodeName=node-1", labels.String())
+}
+
+func TestVppVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if r.URL.Path != "/vpp/command" || json.NewDecoder(r.Body).Decode(&body) != nil || body["vppclicommand"] != "show version" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode("vpp v20.05.1-release built by root on 1a2b3c at 2020-07-01T00:00:00\n")
+	}))
+	defer server.Close()
+
+	version, err := nselabels.VppVersion(context.Background(), server.Client(), server.URL)
+	require.NoError(t, err)
+	require.Equal(t, "v20.05.1-release", version)
+}
+
+func TestVppVersion_Error(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := nselabels.VppVersion(context.Background(), server.Client(), server.URL)
+	require.Error(t, err)
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nsename chooses the name the forwarder registers with: by default one unique to its node or pod, and in
+// any case one no forwarder on another node is registered with
+package nsename
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/networkservicemesh/api/pkg/api/registry"
+	"github.com/pkg/errors"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/log"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/nselabels"
+)
+
+const (
+	// PolicyFail - policy failing when the name is taken
+	PolicyFail = "fail"
+	// PolicySuffix - policy appending -2, -3, ... to the name until it is not taken
+	PolicySuffix = "suffix"
+
+	defaultPrefix = "forwarder-"
+	// maxSuffix - the last suffix tried by PolicySuffix
+	maxSuffix = 10
+)
+
+// Default - returns the default name of the forwarder: forwarder-<nodeName> if nodeName is known, which stays the
+//           same across restarts, else podName, else forwarder-<hostname>
+func Default(nodeName, podName, hostname string) string {
+	switch {
+	case nodeName != "":
+		return defaultPrefix + nodeName
+	case podName != "":
+		return podName
+	default:
+		return defaultPrefix + hostname
+	}
+}
+
+// Unique - returns name if no forwarder running on another node than nodeName is registered with it through client.
+//          Otherwise, depending on policy, it fails or returns name with the first suffix that is not taken.  A
+//          registration of a forwarder on nodeName is taken to be that of a previous instance of ours, which ours
+//          replaces, and so is one without a node name, such as that of a forwarder predating the node name label.
+func Unique(ctx context.Context, client registry.NetworkServiceEndpointRegistryClient, name, nodeName, policy string) (string, error) {
+	if policy != PolicyFail && policy != PolicySuffix {
+		return "", errors.Errorf("unknown name policy %q, expected %s or %s", policy, PolicyFail, PolicySuffix)
+	}
+	candidate := name
+	for suffix := 2; ; suffix++ {
+		owner, err := takenBy(ctx, client, candidate, nodeName)
+		if err != nil {
+			return "", err
+		}
+		if owner == nil {
+			return candidate, nil
+		}
+		if policy == PolicyFail {
+			return "", errors.Errorf("name %s is already registered by a forwarder on node %q at %s",
+				candidate, nodeNameOf(owner), owner.GetUrl())
+		}
+		if suffix > maxSuffix {
+			return "", errors.Errorf("names %s to %s are all already registered", name, candidate)
+		}
+		candidate = fmt.Sprintf("%s-%d", name, suffix)
+	}
+}
+
+// takenBy - returns the NetworkServiceEndpoint registered with name by a forwarder on another node than nodeName, if
+// any.  Registrations without a node name may or may not be ours, and are only warned about.
+func takenBy(ctx context.Context, client registry.NetworkServiceEndpointRegistryClient, name, nodeName string) (*registry.NetworkServiceEndpoint, error) {
+	stream, err := client.Find(ctx, &registry.NetworkServiceEndpointQuery{
+		NetworkServiceEndpoint: &registry.NetworkServiceEndpoint{Name: name},
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to look %s up in the registry", name)
+	}
+	for {
+		nse, err := stream.Recv()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to look %s up in the registry", name)
+		}
+		// The registry matches names by substring
+		if nse.GetName() != name {
+			continue
+		}
+		switch nseNodeName := nodeNameOf(nse); {
+		case nseNodeName == "":
+			log.Entry(ctx).Warnf("name %s is already registered at %s by a forwarder on an unknown node, "+
+				"assuming it is a previous instance of this one", name, nse.GetUrl())
+		case nseNodeName != nodeName:
+			return nse, nil
+		}
+	}
+}
+
+// nodeNameOf - returns the node name nse was labelled with by nselabels, if any
+func nodeNameOf(nse *registry.NetworkServiceEndpoint) string {
+	for _, labels := range nse.GetNetworkServiceLabels() {
+		if nodeName, ok := labels.GetLabels()[nselabels.NodeNameKey]; ok {
+			return nodeName
+		}
+	}
+	return ""
+}
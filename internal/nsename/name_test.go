@@ -0,0 +1,99 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nsename_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/networkservicemesh/api/pkg/api/registry"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/networkservicemesh/sdk/pkg/registry/core/streamchannel"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/nselabels"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/nsename"
+)
+
+// fakeRegistry - finds the registered NetworkServiceEndpoints whose name contains the one queried, as the registry does
+type fakeRegistry struct {
+	registry.NetworkServiceEndpointRegistryClient
+	nses []*registry.NetworkServiceEndpoint
+}
+
+func (f *fakeRegistry) Find(ctx context.Context, query *registry.NetworkServiceEndpointQuery, _ ...grpc.CallOption) (registry.NetworkServiceEndpointRegistry_FindClient, error) {
+	ch := make(chan *registry.NetworkServiceEndpoint, len(f.nses))
+	for _, nse := range f.nses {
+		if strings.Contains(nse.GetName(), query.GetNetworkServiceEndpoint().GetName()) {
+			ch <- nse
+		}
+	}
+	close(ch)
+	return streamchannel.NewNetworkServiceEndpointFindClient(ctx, ch), nil
+}
+
+func nse(name, nodeName string) *registry.NetworkServiceEndpoint {
+	return &registry.NetworkServiceEndpoint{
+		Name: name,
+		NetworkServiceLabels: map[string]*registry.NetworkServiceLabels{
+			"xconnectns": {Labels: map[string]string{nselabels.NodeNameKey: nodeName}},
+		},
+	}
+}
+
+func TestDefault(t *testing.T) {
+	require.Equal(t, "forwarder-node1", nsename.Default("node1", "forwarder-x7k2p", "host1"))
+	require.Equal(t, "forwarder-x7k2p", nsename.Default("", "forwarder-x7k2p", "host1"))
+	require.Equal(t, "forwarder-host1", nsename.Default("", "", "host1"))
+}
+
+func TestUnique(t *testing.T) {
+	client := &fakeRegistry{nses: []*registry.NetworkServiceEndpoint{
+		nse("forwarder", "node2"),
+		nse("forwarder-2", "node3"),
+		nse("forwarder-node1", "node1"),
+		{Name: "forwarder-unlabelled"},
+	}}
+	ctx := context.Background()
+
+	// Free, although other names contain it
+	name, err := nsename.Unique(ctx, client, "forward", "node1", nsename.PolicyFail)
+	require.NoError(t, err)
+	require.Equal(t, "forward", name)
+
+	// Registered by a previous instance on the same node
+	name, err = nsename.Unique(ctx, client, "forwarder-node1", "node1", nsename.PolicyFail)
+	require.NoError(t, err)
+	require.Equal(t, "forwarder-node1", name)
+
+	// Registered without a node name, as by a previous instance predating the label
+	name, err = nsename.Unique(ctx, client, "forwarder-unlabelled", "node1", nsename.PolicyFail)
+	require.NoError(t, err)
+	require.Equal(t, "forwarder-unlabelled", name)
+
+	// Taken
+	_, err = nsename.Unique(ctx, client, "forwarder", "node1", nsename.PolicyFail)
+	require.Error(t, err)
+	name, err = nsename.Unique(ctx, client, "forwarder", "node1", nsename.PolicySuffix)
+	require.NoError(t, err)
+	require.Equal(t, "forwarder-3", name)
+
+	_, err = nsename.Unique(ctx, client, "forwarder", "node1", "random")
+	require.Error(t, err)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package peerauthz restricts the SPIFFE IDs of the mTLS peers of the forwarder
+package peerauthz
+
+import (
+	"crypto/x509"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/spiffepattern"
+)
+
+// Policy - the SPIFFE IDs allowed as peers
+type Policy struct {
+	patterns *spiffepattern.Set
+}
+
+// NewPolicy - returns a Policy allowing the SPIFFE IDs matching any of patterns, as accepted by spiffepattern.Set.
+//             A Policy without any pattern allows any SPIFFE ID.
+func NewPolicy(patterns ...string) (*Policy, error) {
+	p := &Policy{patterns: spiffepattern.New()}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := p.patterns.Add(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// AllowsAny - returns whether p allows any SPIFFE ID
+func (p *Policy) AllowsAny() bool {
+	return p.patterns.Len() == 0
+}
+
+// Allows - returns whether p allows id
+func (p *Policy) Allows(id spiffeid.ID) bool {
+	if p.AllowsAny() {
+		return true
+	}
+	_, ok := p.patterns.Match(id)
+	return ok
+}
+
+// Authorizer - returns a tlsconfig.Authorizer accepting the peers p allows.  The SPIFFE ID of each rejected peer is
+// passed to onReject.
+func (p *Policy) Authorizer(onReject func(id spiffeid.ID)) tlsconfig.Authorizer {
+	return func(id spiffeid.ID, _ [][]*x509.Certificate) error {
+		if p.Allows(id) {
+			return nil
+		}
+		onReject(id)
+		return errors.Errorf("peer %s is not authorized", id)
+	}
+}
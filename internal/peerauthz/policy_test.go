@@ -0,0 +1,64 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peerauthz_test
+
+import (
+	"testing"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/peerauthz"
+)
+
+func TestPolicy(t *testing.T) {
+	policy, err := peerauthz.NewPolicy(
+		"spiffe://example.org/ns/nsm-system/sa/nsmgr",
+		"spiffe://example.org/ns/registry/*",
+		"spiffe://trusted.org",
+	)
+	require.NoError(t, err)
+	for id, allowed := range map[string]bool{
+		"spiffe://example.org/ns/nsm-system/sa/nsmgr":       true,
+		"spiffe://example.org/ns/nsm-system/sa/nsmgr2":      false,
+		"spiffe://example.org/ns/registry/sa/registry-k8s":  true,
+		"spiffe://example.org/ns/registry":                  false,
+		"spiffe://example.org/ns/registry-other/sa/default": false,
+		"spiffe://trusted.org/anything":                     true,
+		"spiffe://other.org/ns/nsm-system/sa/nsmgr":         false,
+	} {
+		require.Equal(t, allowed, policy.Allows(spiffeid.RequireFromString(id)), id)
+	}
+
+	var rejected []string
+	authorizer := policy.Authorizer(func(id spiffeid.ID) { rejected = append(rejected, id.String()) })
+	require.NoError(t, authorizer(spiffeid.RequireFromString("spiffe://trusted.org/nse"), nil))
+	require.Error(t, authorizer(spiffeid.RequireFromString("spiffe://other.org/nse"), nil))
+	require.Equal(t, []string{"spiffe://other.org/nse"}, rejected)
+}
+
+func TestPolicy_Empty(t *testing.T) {
+	policy, err := peerauthz.NewPolicy()
+	require.NoError(t, err)
+	require.True(t, policy.AllowsAny())
+	require.True(t, policy.Allows(spiffeid.RequireFromString("spiffe://example.org/anything")))
+}
+
+func TestPolicy_Invalid(t *testing.T) {
+	_, err := peerauthz.NewPolicy("example.org/ns")
+	require.Error(t, err)
+}
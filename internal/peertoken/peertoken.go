@@ -0,0 +1,68 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package peertoken verifies that the path of a Request was vouched for by the mTLS peer forwarding it
+package peertoken
+
+import (
+	"context"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/pkg/errors"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Verify - returns an error unless the token of the path segment of the caller of conn, the current one, is signed
+//          with the key of the certificate of the mTLS peer in ctx and issued to the peer's SPIFFE ID.  The forwarder
+//          cannot verify the tokens of the segments before it itself, so it relies on the peer to have done so.
+func Verify(ctx context.Context, conn *networkservice.Connection) error {
+	path := conn.GetPath()
+	segments := path.GetPathSegments()
+	if int(path.GetIndex()) >= len(segments) {
+		return errors.New("connection has no path segment for its caller")
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return errors.New("no mTLS peer")
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return errors.New("no mTLS peer certificate")
+	}
+	cert := tlsInfo.State.PeerCertificates[0]
+	peerID, err := x509svid.IDFromCert(cert)
+	if err != nil {
+		return errors.Wrap(err, "invalid mTLS peer certificate")
+	}
+	segment := segments[path.GetIndex()]
+	claims := &jwt.StandardClaims{}
+	_, err = jwt.ParseWithClaims(segment.GetToken(), claims, func(token *jwt.Token) (interface{}, error) {
+		if _, isECDSA := token.Method.(*jwt.SigningMethodECDSA); !isECDSA {
+			return nil, errors.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return cert.PublicKey, nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "token of %s not signed by the mTLS peer", segment.GetName())
+	}
+	if claims.Subject != peerID.String() {
+		return errors.Errorf("token of %s issued to %s rather than to the mTLS peer %s", segment.GetName(), claims.Subject, peerID)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peertoken_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/stretchr/testify/require"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/peertoken"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/peertoken/peertokentest"
+)
+
+const clientID = "spiffe://example.org/ns/default/sa/nsc"
+
+func TestVerify(t *testing.T) {
+	nsmgr := peertokentest.New(t)
+	ctx := nsmgr.Context(context.Background())
+	require.NoError(t, peertoken.Verify(ctx, nsmgr.Forward(t, &networkservice.Connection{Id: "1"}, clientID, nsmgr.Key)))
+
+	// Signed with another key than that of the peer
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	require.Error(t, peertoken.Verify(ctx, nsmgr.Forward(t, &networkservice.Connection{Id: "1"}, clientID, otherKey)))
+
+	// Without a peer
+	require.Error(t, peertoken.Verify(context.Background(), nsmgr.Forward(t, &networkservice.Connection{Id: "1"}, clientID, nsmgr.Key)))
+
+	// Without a segment for the caller
+	require.Error(t, peertoken.Verify(ctx, &networkservice.Connection{Id: "1"}))
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package peertokentest provides an NSMgr forwarding Requests as tests of peertoken.Verify(...) expect
+package peertokentest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// ID - SPIFFE ID of the NSMgr
+const ID = "spiffe://example.org/ns/nsm-system/sa/nsmgr"
+
+// NSMgr - the mTLS peer forwarding the Requests of the clients
+type NSMgr struct {
+	Key  *ecdsa.PrivateKey
+	Cert *x509.Certificate
+}
+
+// New - returns an NSMgr with a new key and a self-signed certificate for ID
+func New(t *testing.T) *NSMgr {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	id, err := url.Parse(ID)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{id},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &NSMgr{Key: key, Cert: cert}
+}
+
+// Context - returns a child of ctx with the NSMgr as mTLS peer
+func (n *NSMgr) Context(ctx context.Context) context.Context {
+	return peer.NewContext(ctx, &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{n.Cert}}},
+	})
+}
+
+// Forward - sets the path of conn to that of a Request of the client clientID forwarded by the NSMgr, with a token
+// signed by key
+func (n *NSMgr) Forward(t *testing.T, conn *networkservice.Connection, clientID string, key *ecdsa.PrivateKey) *networkservice.Connection {
+	clientToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.StandardClaims{Subject: clientID}).SignedString([]byte("key"))
+	require.NoError(t, err)
+	nsmgrToken, err := jwt.NewWithClaims(jwt.SigningMethodES256, &jwt.StandardClaims{Subject: ID}).SignedString(key)
+	require.NoError(t, err)
+	conn.Path = &networkservice.Path{
+		Index: 1,
+		PathSegments: []*networkservice.PathSegment{
+			{Name: "nsc", Id: conn.GetId(), Token: clientToken},
+			{Name: "nsmgr", Id: conn.GetId() + "-nsmgr", Token: nsmgrToken},
+		},
+	}
+	return conn
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"context"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/pkg/errors"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/peertoken"
+)
+
+// ClientID - returns the SPIFFE ID of the client that originated conn, which is the subject of the token of the
+//            first segment of its path.  The forwarder cannot verify that token itself, so it relies on the mTLS peer
+//            in ctx to vouch for it, as told by peertoken.Verify(...).
+func ClientID(ctx context.Context, conn *networkservice.Connection) (string, error) {
+	if err := peertoken.Verify(ctx, conn); err != nil {
+		return "", err
+	}
+	id := subject(conn.GetPath().GetPathSegments()[0])
+	if id == "" {
+		return "", errors.New("token of the client has no subject")
+	}
+	return id, nil
+}
+
+// subject - returns the subject of the token of segment, without verifying it
+func subject(segment *networkservice.PathSegment) string {
+	claims := &jwt.StandardClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(segment.GetToken(), claims); err != nil {
+		return ""
+	}
+	return claims.Subject
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"time"
+
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+)
+
+// Option - option for use with NewServer(...)
+type Option func(s *Server)
+
+// WithConnections - sets the functions returning the active connections and telling whether a connection is one of
+// them, whose refreshes do not count against the MaxConnections of its client.
+func WithConnections(connections func() []*networkservice.Connection, isActive func(conn *networkservice.Connection) bool) Option {
+	return func(s *Server) {
+		s.connections = connections
+		s.isActive = isActive
+	}
+}
+
+// WithIdleTimeout - sets the time after which a client without any connection nor Request is forgotten, 5 minutes by
+// default
+func WithIdleTimeout(idleTimeout time.Duration) Option {
+	return func(s *Server) {
+		s.idleTimeout = idleTimeout
+	}
+}
+
+// WithRejectFunc - sets a function called with the reason, ReasonClientConnections or ReasonClientRequestRate, of
+// each Request rejected
+func WithRejectFunc(rejectFunc func(reason string)) Option {
+	return func(s *Server) {
+		s.rejectFunc = rejectFunc
+	}
+}
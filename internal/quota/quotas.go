@@ -0,0 +1,103 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/spiffepattern"
+)
+
+// Quota - the limits applying to each client
+type Quota struct {
+	// MaxConnections - maximum number of active connections, 0 for no limit
+	MaxConnections int
+	// MaxRequestsPerSecond - maximum sustained rate of Requests, 0 for no limit.  Bursts of up to as many Requests,
+	// and at least one, are allowed.
+	MaxRequestsPerSecond float64
+}
+
+// Quotas - the Quota of each client, by SPIFFE ID pattern
+type Quotas struct {
+	patterns *spiffepattern.Set
+	// quotas - the Quota of each pattern, by its index
+	quotas []Quota
+}
+
+// Parse - returns the Quotas given by specs, each of which is <pattern>=<max connections>/<max Requests per second>,
+//         e.g. spiffe://example.org/ns/default/*=10/5, either limit being 0 for no limit.  Patterns are those of
+//         spiffepattern.Set, as for peer authorization.
+func Parse(specs ...string) (*Quotas, error) {
+	q := &Quotas{patterns: spiffepattern.New()}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		if err := q.add(spec); err != nil {
+			return nil, errors.Wrapf(err, "invalid quota %q", spec)
+		}
+	}
+	return q, nil
+}
+
+func (q *Quotas) add(spec string) error {
+	i := strings.LastIndex(spec, "=")
+	if i < 0 {
+		return errors.New("expected <pattern>=<max connections>/<max Requests per second>")
+	}
+	pattern, limits := spec[:i], strings.Split(spec[i+1:], "/")
+	if len(limits) != 2 {
+		return errors.New("expected <max connections>/<max Requests per second>")
+	}
+	var quota Quota
+	var err error
+	if quota.MaxConnections, err = strconv.Atoi(limits[0]); err != nil || quota.MaxConnections < 0 {
+		return errors.Errorf("invalid max connections %q", limits[0])
+	}
+	if quota.MaxRequestsPerSecond, err = strconv.ParseFloat(limits[1], 64); err != nil || quota.MaxRequestsPerSecond < 0 {
+		return errors.Errorf("invalid max Requests per second %q", limits[1])
+	}
+	if _, err := q.patterns.Add(pattern); err != nil {
+		return err
+	}
+	q.quotas = append(q.quotas, quota)
+	return nil
+}
+
+// Empty - returns whether q has no Quota at all
+func (q *Quotas) Empty() bool {
+	return q.patterns.Len() == 0
+}
+
+// For - returns the Quota of the client with SPIFFE ID id, from the most specific pattern matching it, along with
+// that pattern, if any
+func (q *Quotas) For(id string) (quota Quota, pattern string, ok bool) {
+	spiffeID, err := spiffeid.FromString(id)
+	if err != nil {
+		return Quota{}, "", false
+	}
+	index, ok := q.patterns.Match(spiffeID)
+	if !ok {
+		return Quota{}, "", false
+	}
+	return q.quotas[index], q.patterns.Pattern(index), true
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/quota"
+)
+
+func TestParse(t *testing.T) {
+	quotas, err := quota.Parse(
+		"spiffe://example.org=100/50",
+		"spiffe://example.org/ns/default/*=10/5",
+		"spiffe://example.org/ns/default/sa/batch/*=2/0.5",
+		"spiffe://example.org/ns/default/sa/web=20/0",
+	)
+	require.NoError(t, err)
+	require.False(t, quotas.Empty())
+
+	for _, test := range []struct {
+		id      string
+		quota   quota.Quota
+		pattern string
+	}{
+		{"spiffe://example.org/ns/default/sa/web", quota.Quota{MaxConnections: 20}, "spiffe://example.org/ns/default/sa/web"},
+		{"spiffe://example.org/ns/default/sa/batch/job", quota.Quota{MaxConnections: 2, MaxRequestsPerSecond: 0.5},
+			"spiffe://example.org/ns/default/sa/batch/*"},
+		{"spiffe://example.org/ns/default/sa/db", quota.Quota{MaxConnections: 10, MaxRequestsPerSecond: 5},
+			"spiffe://example.org/ns/default/*"},
+		{"spiffe://example.org/ns/other/sa/db", quota.Quota{MaxConnections: 100, MaxRequestsPerSecond: 50},
+			"spiffe://example.org"},
+	} {
+		q, pattern, ok := quotas.For(test.id)
+		require.True(t, ok, test.id)
+		require.Equal(t, test.quota, q, test.id)
+		require.Equal(t, test.pattern, pattern, test.id)
+	}
+
+	_, _, ok := quotas.For("spiffe://other.org/ns/default/sa/web")
+	require.False(t, ok)
+	_, _, ok = quotas.For("")
+	require.False(t, ok)
+}
+
+func TestParse_Empty(t *testing.T) {
+	quotas, err := quota.Parse()
+	require.NoError(t, err)
+	require.True(t, quotas.Empty())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"spiffe://example.org",
+		"spiffe://example.org=10",
+		"spiffe://example.org=ten/5",
+		"spiffe://example.org=10/-1",
+		"example.org=10/5",
+	} {
+		_, err := quota.Parse(spec)
+		require.Error(t, err, spec)
+	}
+}
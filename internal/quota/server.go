@@ -0,0 +1,286 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package quota provides a NetworkServiceServer chain element enforcing per-client quotas on the number of active
+// connections and the rate of Requests, clients being identified by the SPIFFE ID of their path segment, along with
+// an HTTP API reporting their usage
+package quota
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/networkservicemesh/sdk/pkg/networkservice/core/next"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/conntrack"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/httpserve"
+)
+
+const (
+	// Path - HTTP path the usage of the clients with a Quota is served on
+	Path = "/quotas"
+	// defaultIdleTimeout - default time after which a client without any connection nor Request is forgotten
+	defaultIdleTimeout = 5 * time.Minute
+
+	// ReasonClientConnections - reason for rejecting a Request for a new connection when its client has its maximum
+	// number of connections
+	ReasonClientConnections = "client_connections"
+	// ReasonClientRequestRate - reason for rejecting a Request when its client exceeds its rate of Requests
+	ReasonClientRequestRate = "client_request_rate"
+)
+
+// Usage - the usage a client makes of its Quota
+type Usage struct {
+	SpiffeID string `json:"spiffeId"`
+	// Pattern - the pattern its Quota is configured for
+	Pattern              string  `json:"pattern"`
+	Connections          int     `json:"connections"`
+	MaxConnections       int     `json:"maxConnections,omitempty"`
+	MaxRequestsPerSecond float64 `json:"maxRequestsPerSecond,omitempty"`
+	// Requests, Rejected - number of Requests accepted and rejected since the forwarder started
+	Requests uint64 `json:"requests"`
+	Rejected uint64 `json:"rejected"`
+}
+
+type client struct {
+	quota   Quota
+	pattern string
+	// tokens, last - the token bucket of the client's rate of Requests, as of its last Request
+	tokens float64
+	last   time.Time
+	// pending - ids of the new connections Requested, until connections returns them or the Request fails
+	pending  map[string]bool
+	requests uint64
+	rejected uint64
+}
+
+// Server - NetworkServiceServer chain element rejecting the Requests of the clients exceeding their Quota with
+// codes.ResourceExhausted
+type Server struct {
+	quotas      *Quotas
+	connections func() []*networkservice.Connection
+	isActive    func(conn *networkservice.Connection) bool
+	rejectFunc  func(reason string)
+	idleTimeout time.Duration
+
+	mu        sync.Mutex
+	clients   map[string]*client
+	lastPrune time.Time
+}
+
+// NewServer - returns a new Server enforcing quotas.  It should be placed after the authorization of the caller.
+func NewServer(quotas *Quotas, options ...Option) *Server {
+	s := &Server{
+		quotas:      quotas,
+		connections: func() []*networkservice.Connection { return nil },
+		isActive:    func(*networkservice.Connection) bool { return false },
+		rejectFunc:  func(string) {},
+		idleTimeout: defaultIdleTimeout,
+		clients:     make(map[string]*client),
+		lastPrune:   time.Now(),
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// Request - rejects the request with codes.ResourceExhausted if its client exceeds its rate of Requests, or if it
+// is for a new connection and the client has its maximum number of connections, counting those being Requested.
+// When there are quotas, a request whose client cannot be identified, as told by ClientID(...), is rejected with
+// codes.PermissionDenied.  Replays and restores of tracked connections, which were admitted before, are always let
+// through.
+func (s *Server) Request(ctx context.Context, request *networkservice.NetworkServiceRequest) (*networkservice.Connection, error) {
+	if s.quotas.Empty() || conntrack.IsReplay(ctx) {
+		return next.Server(ctx).Request(ctx, request)
+	}
+	id, err := ClientID(ctx, request.GetConnection())
+	if err != nil {
+		return nil, status.Errorf(codes.PermissionDenied, "cannot identify the client for its quota: %s", err)
+	}
+	c := s.client(id)
+	if c == nil {
+		return next.Server(ctx).Request(ctx, request)
+	}
+	reserved, reason := s.admit(id, c, request.GetConnection())
+	switch reason {
+	case ReasonClientRequestRate:
+		s.rejectFunc(reason)
+		return nil, status.Errorf(codes.ResourceExhausted, "client %s exceeds its quota of %g Requests per second",
+			id, c.quota.MaxRequestsPerSecond)
+	case ReasonClientConnections:
+		s.rejectFunc(reason)
+		return nil, status.Errorf(codes.ResourceExhausted, "client %s already has its quota of %d connections",
+			id, c.quota.MaxConnections)
+	}
+	conn, err := next.Server(ctx).Request(ctx, request)
+	if err != nil && reserved {
+		s.release(request.GetConnection())
+	}
+	return conn, err
+}
+
+// Close - always passes the Close on, as it frees resources
+func (s *Server) Close(ctx context.Context, conn *networkservice.Connection) (*empty.Empty, error) {
+	s.release(conn)
+	return next.Server(ctx).Close(ctx, conn)
+}
+
+// client - returns the state of the client with SPIFFE ID id, or nil if it has no Quota
+func (s *Server) client(id string) *client {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	if c, ok := s.clients[id]; ok {
+		return c
+	}
+	quota, pattern, ok := s.quotas.For(id)
+	if !ok {
+		return nil
+	}
+	c := &client{
+		quota:   quota,
+		pattern: pattern,
+		tokens:  burst(quota),
+		last:    time.Now(),
+		pending: make(map[string]bool),
+	}
+	s.clients[id] = c
+	return c
+}
+
+// admit - returns the reason for rejecting a Request for conn from c, if any, and otherwise whether room was reserved
+// for a new connection, which must then be released should the Request fail
+func (s *Server) admit(id string, c *client, conn *networkservice.Connection) (reserved bool, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if c.quota.MaxRequestsPerSecond > 0 {
+		c.tokens = math.Min(burst(c.quota), c.tokens+now.Sub(c.last).Seconds()*c.quota.MaxRequestsPerSecond)
+	}
+	// Rejected Requests too, so that the time elapsed is only credited once
+	c.last = now
+	if c.quota.MaxRequestsPerSecond > 0 {
+		if c.tokens < 1 {
+			c.rejected++
+			return false, ReasonClientRequestRate
+		}
+		c.tokens--
+	}
+	if c.quota.MaxConnections > 0 && !s.isActive(conn) && !c.pending[conn.GetId()] {
+		active := s.clientConnections()[id]
+		for pending := range c.pending {
+			if active[pending] {
+				delete(c.pending, pending)
+			}
+		}
+		if len(active)+len(c.pending) >= c.quota.MaxConnections {
+			c.rejected++
+			return false, ReasonClientConnections
+		}
+		c.pending[conn.GetId()] = true
+		reserved = true
+	}
+	c.requests++
+	return reserved, ""
+}
+
+// release - releases the room reserved for conn, if any
+func (s *Server) release(conn *networkservice.Connection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.clients {
+		delete(c.pending, conn.GetId())
+	}
+}
+
+// clientConnections - returns the ids of the active connections, by the SPIFFE ID of their client
+func (s *Server) clientConnections() map[string]map[string]bool {
+	rv := make(map[string]map[string]bool)
+	for _, conn := range s.connections() {
+		segments := conn.GetPath().GetPathSegments()
+		if len(segments) == 0 {
+			continue
+		}
+		id := subject(segments[0])
+		if rv[id] == nil {
+			rv[id] = make(map[string]bool)
+		}
+		rv[id][conn.GetId()] = true
+	}
+	return rv
+}
+
+// prune - forgets, at most once per idleTimeout, the clients that have had no active or pending connection, nor
+// made any Request, for idleTimeout, so that the clients do not pile up
+func (s *Server) prune() {
+	now := time.Now()
+	if now.Sub(s.lastPrune) < s.idleTimeout {
+		return
+	}
+	s.lastPrune = now
+	active := s.clientConnections()
+	for id, c := range s.clients {
+		if len(active[id]) == 0 && len(c.pending) == 0 && now.Sub(c.last) >= s.idleTimeout {
+			delete(s.clients, id)
+		}
+	}
+}
+
+// Usage - returns the usage of the clients with a Quota that have made Requests, ordered by SPIFFE ID.  Clients idle
+// for long enough are forgotten, along with their counts of Requests.
+func (s *Server) Usage() []*Usage {
+	active := s.clientConnections()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage := make([]*Usage, 0, len(s.clients))
+	for id, c := range s.clients {
+		usage = append(usage, &Usage{
+			SpiffeID:             id,
+			Pattern:              c.pattern,
+			Connections:          len(active[id]),
+			MaxConnections:       c.quota.MaxConnections,
+			MaxRequestsPerSecond: c.quota.MaxRequestsPerSecond,
+			Requests:             c.requests,
+			Rejected:             c.rejected,
+		})
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].SpiffeID < usage[j].SpiffeID })
+	return usage
+}
+
+// Handler - returns an http.Handler serving the Usage of the clients as JSON on Path
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(Path, func(w http.ResponseWriter, r *http.Request) {
+		httpserve.WriteJSON(w, http.StatusOK, s.Usage())
+	})
+	return mux
+}
+
+// burst - returns the number of Requests a client with quota may make at once
+func burst(quota Quota) float64 {
+	return math.Max(1, quota.MaxRequestsPerSecond)
+}
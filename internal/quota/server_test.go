@@ -0,0 +1,271 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package quota_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/networkservicemesh/sdk/pkg/networkservice/core/chain"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/conntrack"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/peertoken/peertokentest"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/quota"
+)
+
+const (
+	batchID = "spiffe://example.org/ns/default/sa/batch"
+	webID   = "spiffe://example.org/ns/default/sa/web"
+)
+
+// trackingServer - keeps track of the connections Requested
+type trackingServer struct {
+	mu    sync.Mutex
+	conns map[string]*networkservice.Connection
+}
+
+func (s *trackingServer) Request(_ context.Context, request *networkservice.NetworkServiceRequest) (*networkservice.Connection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conns[request.GetConnection().GetId()] = request.GetConnection()
+	return request.GetConnection(), nil
+}
+
+func (s *trackingServer) Close(_ context.Context, conn *networkservice.Connection) (*empty.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn.GetId())
+	return &empty.Empty{}, nil
+}
+
+func (s *trackingServer) connections() []*networkservice.Connection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var conns []*networkservice.Connection
+	for _, conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	return conns
+}
+
+func (s *trackingServer) isActive(conn *networkservice.Connection) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.conns[conn.GetId()]
+	return ok
+}
+
+// nsmgr - the mTLS peer forwarding the Requests of the clients
+type nsmgr struct {
+	*peertokentest.NSMgr
+}
+
+func newNSMgr(t *testing.T) *nsmgr {
+	return &nsmgr{NSMgr: peertokentest.New(t)}
+}
+
+// ctx - returns a context with the NSMgr as mTLS peer
+func (n *nsmgr) ctx() context.Context {
+	return n.Context(context.Background())
+}
+
+// connection - returns a connection of the client spiffeID, forwarded by the NSMgr with a token signed by key
+func (n *nsmgr) connection(t *testing.T, id, spiffeID string, key *ecdsa.PrivateKey) *networkservice.Connection {
+	return n.Forward(t, &networkservice.Connection{Id: id}, spiffeID, key)
+}
+
+func newServer(t *testing.T, rejected *[]string, specs ...string) (networkservice.NetworkServiceServer, *quota.Server) {
+	quotas, err := quota.Parse(specs...)
+	require.NoError(t, err)
+	tracking := &trackingServer{conns: make(map[string]*networkservice.Connection)}
+	quotaServer := quota.NewServer(quotas,
+		quota.WithConnections(tracking.connections, tracking.isActive),
+		quota.WithRejectFunc(func(reason string) { *rejected = append(*rejected, reason) }),
+		quota.WithIdleTimeout(100*time.Millisecond),
+	)
+	return chain.NewNetworkServiceServer(quotaServer, tracking), quotaServer
+}
+
+func request(ctx context.Context, server networkservice.NetworkServiceServer, conn *networkservice.Connection) error {
+	_, err := server.Request(ctx, &networkservice.NetworkServiceRequest{Connection: conn})
+	return err
+}
+
+func TestServer_MaxConnections(t *testing.T) {
+	n := newNSMgr(t)
+	var rejected []string
+	server, _ := newServer(t, &rejected, "spiffe://example.org/ns/default/sa/batch=2/0")
+
+	require.NoError(t, request(n.ctx(), server, n.connection(t, "1", batchID, n.Key)))
+	require.NoError(t, request(n.ctx(), server, n.connection(t, "2", batchID, n.Key)))
+	err := request(n.ctx(), server, n.connection(t, "3", batchID, n.Key))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{quota.ReasonClientConnections}, rejected)
+
+	// Refreshes are accepted, and other clients are not limited
+	require.NoError(t, request(n.ctx(), server, n.connection(t, "1", batchID, n.Key)))
+	require.NoError(t, request(n.ctx(), server, n.connection(t, "4", webID, n.Key)))
+
+	_, err = server.Close(n.ctx(), n.connection(t, "1", batchID, n.Key))
+	require.NoError(t, err)
+	require.NoError(t, request(n.ctx(), server, n.connection(t, "3", batchID, n.Key)))
+}
+
+func TestServer_MaxRequestsPerSecond(t *testing.T) {
+	n := newNSMgr(t)
+	var rejected []string
+	server, _ := newServer(t, &rejected, "spiffe://example.org/ns/default/*=0/2")
+
+	require.NoError(t, request(n.ctx(), server, n.connection(t, "1", batchID, n.Key)))
+	require.NoError(t, request(n.ctx(), server, n.connection(t, "1", batchID, n.Key)))
+	err := request(n.ctx(), server, n.connection(t, "1", batchID, n.Key))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{quota.ReasonClientRequestRate}, rejected)
+
+	// Each client has a rate of its own
+	require.NoError(t, request(n.ctx(), server, n.connection(t, "2", webID, n.Key)))
+}
+
+func TestServer_MaxRequestsPerSecond_Retries(t *testing.T) {
+	n := newNSMgr(t)
+	var rejected []string
+	server, _ := newServer(t, &rejected, "spiffe://example.org/ns/default/*=0/10")
+	conn := n.connection(t, "1", batchID, n.Key)
+
+	// A burst uses up the tokens
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, request(n.ctx(), server, conn))
+	}
+	require.Equal(t, codes.ResourceExhausted, status.Code(request(n.ctx(), server, conn)))
+
+	// Retrying in a tight loop earns no more tokens than waiting would
+	admitted := 0
+	for time.Since(start) < 200*time.Millisecond {
+		if request(n.ctx(), server, conn) == nil {
+			admitted++
+		}
+		time.Sleep(time.Millisecond)
+	}
+	require.LessOrEqual(t, admitted, int(time.Since(start).Seconds()*10))
+}
+
+func TestServer_Usage(t *testing.T) {
+	n := newNSMgr(t)
+	var rejected []string
+	server, quotaServer := newServer(t, &rejected, "spiffe://example.org/ns/default/*=1/0")
+
+	require.NoError(t, request(n.ctx(), server, n.connection(t, "1", batchID, n.Key)))
+	require.Error(t, request(n.ctx(), server, n.connection(t, "2", batchID, n.Key)))
+	require.NoError(t, request(n.ctx(), server, n.connection(t, "3", webID, n.Key)))
+	require.NoError(t, request(n.ctx(), server, n.connection(t, "4", "spiffe://other.org/nsc", n.Key)))
+
+	recorder := httptest.NewRecorder()
+	quotaServer.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, quota.Path, nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var usage []*quota.Usage
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &usage))
+	require.Equal(t, []*quota.Usage{
+		{
+			SpiffeID:       batchID,
+			Pattern:        "spiffe://example.org/ns/default/*",
+			Connections:    1,
+			MaxConnections: 1,
+			Requests:       1,
+			Rejected:       1,
+		},
+		{
+			SpiffeID:       webID,
+			Pattern:        "spiffe://example.org/ns/default/*",
+			Connections:    1,
+			MaxConnections: 1,
+			Requests:       1,
+		},
+	}, usage)
+}
+
+func TestServer_Replay(t *testing.T) {
+	n := newNSMgr(t)
+	var rejected []string
+	quotas, err := quota.Parse("spiffe://example.org/ns/default/*=0/1")
+	require.NoError(t, err)
+	tracker := conntrack.NewServer()
+	tracking := &trackingServer{conns: make(map[string]*networkservice.Connection)}
+	server := chain.NewNetworkServiceServer(
+		tracker,
+		quota.NewServer(quotas, quota.WithRejectFunc(func(reason string) { rejected = append(rejected, reason) })),
+		tracking,
+	)
+
+	require.NoError(t, request(n.ctx(), server, n.connection(t, "1", batchID, n.Key)))
+	require.Equal(t, codes.ResourceExhausted, status.Code(request(n.ctx(), server, n.connection(t, "2", batchID, n.Key))))
+	require.Len(t, rejected, 1)
+	// Replaying the connection after a vppagent restart does not count against the rate of Requests of its client
+	require.NoError(t, tracker.Replay(context.Background(), server))
+	require.Len(t, rejected, 1)
+}
+
+func TestServer_Unverified(t *testing.T) {
+	n := newNSMgr(t)
+	var rejected []string
+	server, _ := newServer(t, &rejected, "spiffe://example.org=1/0")
+
+	// Without an mTLS peer to vouch for it, the client could claim any SPIFFE ID
+	err := request(context.Background(), server, n.connection(t, "1", batchID, n.Key))
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	other := newNSMgr(t)
+	err = request(n.ctx(), server, n.connection(t, "1", batchID, other.Key))
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Empty(t, rejected)
+}
+
+func TestServer_NoQuotas(t *testing.T) {
+	var rejected []string
+	server, _ := newServer(t, &rejected)
+	require.NoError(t, request(context.Background(), server, &networkservice.Connection{Id: "1"}))
+}
+
+func TestServer_IdleClients(t *testing.T) {
+	n := newNSMgr(t)
+	var rejected []string
+	server, quotaServer := newServer(t, &rejected, "spiffe://example.org/ns/default/*=1/0")
+
+	require.NoError(t, request(n.ctx(), server, n.connection(t, "1", batchID, n.Key)))
+	_, err := server.Close(n.ctx(), n.connection(t, "1", batchID, n.Key))
+	require.NoError(t, err)
+	require.Len(t, quotaServer.Usage(), 1)
+
+	// Once idle, batch is forgotten when the next client comes along
+	require.Eventually(t, func() bool {
+		require.NoError(t, request(n.ctx(), server, n.connection(t, "2", webID, n.Key)))
+		usage := quotaServer.Usage()
+		return len(usage) == 1 && usage[0].SpiffeID == webID
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,281 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !windows
+
+// Package reconcile removes the dataplane artifacts left behind by a previous forwarder instance that no longer
+// correspond to any connection being restored
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/pkg/errors"
+	"github.com/vishvananda/netlink"
+	"go.ligato.io/vpp-agent/v3/proto/ligato/configurator"
+	"go.ligato.io/vpp-agent/v3/proto/ligato/linux"
+	linuxinterfaces "go.ligato.io/vpp-agent/v3/proto/ligato/linux/interfaces"
+	"go.ligato.io/vpp-agent/v3/proto/ligato/vpp"
+	vppinterfaces "go.ligato.io/vpp-agent/v3/proto/ligato/vpp/interfaces"
+	vppl2 "go.ligato.io/vpp-agent/v3/proto/ligato/vpp/l2"
+	"google.golang.org/grpc"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/ifnames"
+)
+
+// Report - the artifacts removed by Run
+type Report struct {
+	VppInterfaces   []string
+	XconnectPairs   []string
+	LinuxInterfaces []string
+	KernelLinks     []string
+	TmpDirs         []string
+	MemifSockets    []string
+}
+
+// Run - removes the artifacts of a previous forwarder instance that do not belong to any of keep:
+//       - VPP interfaces, l2 cross connects and Linux interfaces known to the vppagent reached by vppagentCC.  As
+//         that vppagent may be shared with others, VXLAN interfaces are only removed when cross connected with one
+//         of the forwarder's own interfaces.
+//       - veth and tap interfaces left in the forwarder's network namespace.  Removing one end of a veth pair also
+//         removes the end that was in the client's network namespace.
+//       - temporary directories matching tmpDirGlob, along with the memif sockets in them, unless they hold the
+//         memif socket of a connection kept
+//       - memif sockets in memifSocketDir
+//       The identification relies on the names sdk-vppagent gives these artifacts, which contain the id of their
+//       connection.  Run carries on after a failure, so the Report is returned even along with an error.
+func Run(ctx context.Context, vppagentCC grpc.ClientConnInterface, tmpDirGlob, memifSocketDir string, keep []*networkservice.Connection) (*Report, error) {
+	ids := make(map[string]bool)
+	for _, conn := range keep {
+		ids[conn.GetId()] = true
+		for _, segment := range conn.GetPath().GetPathSegments() {
+			ids[segment.GetId()] = true
+		}
+	}
+
+	report := &Report{}
+	var errs []string
+	if err := report.reconcileVppagent(ctx, vppagentCC, ids); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err := report.reconcileKernel(ids); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err := report.removeTmpDirs(tmpDirGlob, ids); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err := report.removeMemifSockets(memifSocketDir, ids); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return report, errors.Errorf("reconciliation incomplete: %s", strings.Join(errs, "; "))
+	}
+	return report, nil
+}
+
+func (r *Report) reconcileVppagent(ctx context.Context, vppagentCC grpc.ClientConnInterface, ids map[string]bool) error {
+	client := configurator.NewConfiguratorServiceClient(vppagentCC)
+	dump, err := client.Dump(ctx, &configurator.DumpRequest{}, grpc.WaitForReady(true))
+	if err != nil {
+		return errors.Wrap(err, "failed to dump vppagent configuration")
+	}
+	stale := &configurator.Config{
+		VppConfig:   &vpp.ConfigData{},
+		LinuxConfig: &linux.ConfigData{},
+	}
+	// Tunnel interfaces are named after their connection id alone, so only those cross connected with one of our
+	// interfaces are known to be ours: a vppagent shared with others may have tunnels of theirs
+	ownTunnels := make(map[string]bool)
+	for _, pair := range dump.GetDump().GetVppConfig().GetXconnectPairs() {
+		if hasNamePrefix(pair.GetReceiveInterface()) {
+			ownTunnels[pair.GetTransmitInterface()] = true
+		}
+		if hasNamePrefix(pair.GetTransmitInterface()) {
+			ownTunnels[pair.GetReceiveInterface()] = true
+		}
+	}
+	staleVppInterfaces := make(map[string]bool)
+	for _, iface := range dump.GetDump().GetVppConfig().GetInterfaces() {
+		isStaleTunnel := iface.GetType() == vppinterfaces.Interface_VXLAN_TUNNEL && ownTunnels[iface.GetName()] && !ids[iface.GetName()]
+		if isStaleTunnel || isStale(iface.GetName(), ids) {
+			stale.VppConfig.Interfaces = append(stale.VppConfig.Interfaces, iface)
+			staleVppInterfaces[iface.GetName()] = true
+			r.VppInterfaces = append(r.VppInterfaces, iface.GetName())
+		}
+	}
+	for _, pair := range dump.GetDump().GetVppConfig().GetXconnectPairs() {
+		if staleVppInterfaces[pair.GetReceiveInterface()] || staleVppInterfaces[pair.GetTransmitInterface()] {
+			stale.VppConfig.XconnectPairs = append(stale.VppConfig.XconnectPairs, pair)
+			r.XconnectPairs = append(r.XconnectPairs, xconnectString(pair))
+		}
+	}
+	for _, iface := range dump.GetDump().GetLinuxConfig().GetInterfaces() {
+		if isStale(iface.GetName(), ids) {
+			stale.LinuxConfig.Interfaces = append(stale.LinuxConfig.Interfaces, iface)
+			r.LinuxInterfaces = append(r.LinuxInterfaces, linuxInterfaceString(iface))
+		}
+	}
+	if len(staleVppInterfaces) == 0 && len(stale.LinuxConfig.Interfaces) == 0 {
+		return nil
+	}
+	_, err = client.Delete(ctx, &configurator.DeleteRequest{Delete: stale}, grpc.WaitForReady(true))
+	return errors.Wrap(err, "failed to delete stale vppagent configuration")
+}
+
+func (r *Report) reconcileKernel(ids map[string]bool) error {
+	keepNames := make(map[string]bool)
+	for id := range ids {
+		for _, prefix := range ifnames.Prefixes {
+			keepNames[ifnames.HostIfName(prefix+id)] = true
+			keepNames[ifnames.HostIfName(prefix+id+ifnames.VethSuffix)] = true
+		}
+	}
+	links, err := netlink.LinkList()
+	if err != nil {
+		return errors.Wrap(err, "failed to list kernel interfaces")
+	}
+	for _, link := range links {
+		name := link.Attrs().Name
+		if !hasNamePrefix(name) || keepNames[name] || (link.Type() != "veth" && link.Type() != "tuntap") {
+			continue
+		}
+		if err = netlink.LinkDel(link); err != nil {
+			return errors.Wrapf(err, "failed to delete kernel interface %s", name)
+		}
+		r.KernelLinks = append(r.KernelLinks, name)
+	}
+	return nil
+}
+
+// removeTmpDirs - removes the temporary directories matching tmpDirGlob, apart from the memif sockets of the
+// connections whose id is in ids, along with the directories holding them
+func (r *Report) removeTmpDirs(tmpDirGlob string, ids map[string]bool) error {
+	if tmpDirGlob == "" {
+		return nil
+	}
+	tmpDirs, err := filepath.Glob(tmpDirGlob)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, tmpDir := range tmpDirs {
+		var dirs []string
+		err = filepath.Walk(tmpDir, func(path string, info os.FileInfo, err error) error {
+			switch {
+			case err != nil:
+				return err
+			case info.IsDir():
+				dirs = append(dirs, path)
+				return nil
+			case strings.HasSuffix(path, ifnames.MemifSocketSuffix) && ids[strings.TrimSuffix(info.Name(), ifnames.MemifSocketSuffix)]:
+				return nil
+			}
+			if err = os.Remove(path); err != nil {
+				return err
+			}
+			if strings.HasSuffix(path, ifnames.MemifSocketSuffix) {
+				r.MemifSockets = append(r.MemifSockets, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to clean up %s", tmpDir)
+		}
+		// Walk lists the directories before their contents, so the innermost come last
+		for i := len(dirs) - 1; i >= 0; i-- {
+			if err = os.Remove(dirs[i]); err != nil && !isNotEmpty(err) {
+				return errors.Wrapf(err, "failed to remove %s", dirs[i])
+			}
+		}
+		if _, err = os.Stat(tmpDir); os.IsNotExist(err) {
+			r.TmpDirs = append(r.TmpDirs, tmpDir)
+		}
+	}
+	return nil
+}
+
+func (r *Report) removeMemifSockets(memifSocketDir string, ids map[string]bool) error {
+	if memifSocketDir == "" {
+		return nil
+	}
+	sockets, err := filepath.Glob(filepath.Join(memifSocketDir, "*"+ifnames.MemifSocketSuffix))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for _, socket := range sockets {
+		if ids[strings.TrimSuffix(filepath.Base(socket), ifnames.MemifSocketSuffix)] {
+			continue
+		}
+		if err = os.Remove(socket); err != nil {
+			return errors.Wrapf(err, "failed to remove %s", socket)
+		}
+		r.MemifSockets = append(r.MemifSockets, socket)
+	}
+	return nil
+}
+
+// String - summarizes the report in a single line
+func (r *Report) String() string {
+	return fmt.Sprintf("%d VPP interfaces %v, %d l2 cross connects %v, %d Linux interfaces %v, %d kernel interfaces %v, %d temporary directories %v, %d memif sockets %v",
+		len(r.VppInterfaces), r.VppInterfaces,
+		len(r.XconnectPairs), r.XconnectPairs,
+		len(r.LinuxInterfaces), r.LinuxInterfaces,
+		len(r.KernelLinks), r.KernelLinks,
+		len(r.TmpDirs), r.TmpDirs,
+		len(r.MemifSockets), r.MemifSockets)
+}
+
+// isStale - returns whether name is that of an interface created for a connection whose id is not in ids, as told
+// by its prefix
+func isStale(name string, ids map[string]bool) bool {
+	for _, prefix := range ifnames.Prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return !ids[strings.TrimSuffix(strings.TrimPrefix(name, prefix), ifnames.VethSuffix)]
+		}
+	}
+	return false
+}
+
+// isNotEmpty - returns whether err is the failure to remove a directory that is not empty
+func isNotEmpty(err error) bool {
+	var errno syscall.Errno
+	return errors.As(err, &errno) && (errno == syscall.ENOTEMPTY || errno == syscall.EEXIST)
+}
+
+func hasNamePrefix(name string) bool {
+	for _, prefix := range ifnames.Prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func xconnectString(pair *vppl2.XConnectPair) string {
+	return pair.GetReceiveInterface() + "->" + pair.GetTransmitInterface()
+}
+
+func linuxInterfaceString(iface *linuxinterfaces.Interface) string {
+	if ref := iface.GetNamespace().GetReference(); ref != "" {
+		return fmt.Sprintf("%s(%s in %s)", iface.GetName(), iface.GetHostIfName(), ref)
+	}
+	return fmt.Sprintf("%s(%s)", iface.GetName(), iface.GetHostIfName())
+}
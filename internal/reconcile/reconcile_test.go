@@ -0,0 +1,235 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !windows
+
+package reconcile_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.ligato.io/vpp-agent/v3/proto/ligato/configurator"
+	"go.ligato.io/vpp-agent/v3/proto/ligato/linux"
+	linuxinterfaces "go.ligato.io/vpp-agent/v3/proto/ligato/linux/interfaces"
+	linuxnamespace "go.ligato.io/vpp-agent/v3/proto/ligato/linux/namespace"
+	"go.ligato.io/vpp-agent/v3/proto/ligato/vpp"
+	vppinterfaces "go.ligato.io/vpp-agent/v3/proto/ligato/vpp/interfaces"
+	vppl2 "go.ligato.io/vpp-agent/v3/proto/ligato/vpp/l2"
+	"google.golang.org/grpc"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/reconcile"
+)
+
+// testVppagent - a vppagent with the configuration dump, recording the configuration deleted
+type testVppagent struct {
+	grpc.ClientConnInterface
+	dump    *configurator.Config
+	deleted *configurator.Config
+}
+
+func (v *testVppagent) Invoke(_ context.Context, method string, args, reply interface{}, _ ...grpc.CallOption) error {
+	switch method {
+	case "/ligato.configurator.ConfiguratorService/Dump":
+		reply.(*configurator.DumpResponse).Dump = v.dump
+	case "/ligato.configurator.ConfiguratorService/Delete":
+		v.deleted = args.(*configurator.DeleteRequest).Delete
+	default:
+		return errors.Errorf("unexpected method %s", method)
+	}
+	return nil
+}
+
+func vppInterface(name string, ifaceType vppinterfaces.Interface_Type) *vppinterfaces.Interface {
+	return &vppinterfaces.Interface{Name: name, Type: ifaceType}
+}
+
+func linuxInterface(name, namespace string) *linuxinterfaces.Interface {
+	iface := &linuxinterfaces.Interface{Name: name, HostIfName: name}
+	if namespace != "" {
+		iface.Namespace = &linuxnamespace.NetNamespace{Type: linuxnamespace.NetNamespace_FD, Reference: namespace}
+	}
+	return iface
+}
+
+func xconnect(receive, transmit string) *vppl2.XConnectPair {
+	return &vppl2.XConnectPair{ReceiveInterface: receive, TransmitInterface: transmit}
+}
+
+func TestRun(t *testing.T) {
+	// conn1 is tracked, along with the segment seg1 of its path
+	keep := []*networkservice.Connection{{
+		Id:   "conn1",
+		Path: &networkservice.Path{PathSegments: []*networkservice.PathSegment{{Id: "seg1"}}},
+	}}
+	for _, testCase := range []struct {
+		name                string
+		vppInterfaces       []*vppinterfaces.Interface
+		xconnectPairs       []*vppl2.XConnectPair
+		linuxInterfaces     []*linuxinterfaces.Interface
+		wantVppInterfaces   []string
+		wantXconnectPairs   []string
+		wantLinuxInterfaces []string
+	}{
+		{
+			name: "tracked interfaces are kept",
+			vppInterfaces: []*vppinterfaces.Interface{
+				vppInterface("server-conn1", vppinterfaces.Interface_TAP),
+				vppInterface("client-seg1", vppinterfaces.Interface_MEMIF),
+			},
+			linuxInterfaces: []*linuxinterfaces.Interface{
+				linuxInterface("server-conn1", "/proc/42/ns/net"),
+				linuxInterface("client-seg1-veth", ""),
+			},
+		},
+		{
+			name: "untracked interfaces are removed",
+			vppInterfaces: []*vppinterfaces.Interface{
+				vppInterface("server-conn2", vppinterfaces.Interface_TAP),
+				vppInterface("client-conn2", vppinterfaces.Interface_AF_PACKET),
+			},
+			linuxInterfaces: []*linuxinterfaces.Interface{
+				linuxInterface("server-conn2", "/proc/42/ns/net"),
+				linuxInterface("client-conn2-veth", ""),
+			},
+			wantVppInterfaces:   []string{"server-conn2", "client-conn2"},
+			wantLinuxInterfaces: []string{"server-conn2(server-conn2 in /proc/42/ns/net)", "client-conn2-veth(client-conn2-veth)"},
+		},
+		{
+			name: "interfaces of others are left",
+			vppInterfaces: []*vppinterfaces.Interface{
+				vppInterface("loop0", vppinterfaces.Interface_UNDEFINED_TYPE),
+				vppInterface("conn2", vppinterfaces.Interface_TAP),
+			},
+			linuxInterfaces: []*linuxinterfaces.Interface{
+				linuxInterface("eth1", "/proc/42/ns/net"),
+			},
+		},
+		{
+			name: "tunnels cross connected with untracked interfaces are removed",
+			vppInterfaces: []*vppinterfaces.Interface{
+				vppInterface("server-conn2", vppinterfaces.Interface_MEMIF),
+				vppInterface("conn2", vppinterfaces.Interface_VXLAN_TUNNEL),
+			},
+			xconnectPairs: []*vppl2.XConnectPair{
+				xconnect("server-conn2", "conn2"),
+				xconnect("conn2", "server-conn2"),
+			},
+			wantVppInterfaces: []string{"server-conn2", "conn2"},
+			wantXconnectPairs: []string{"server-conn2->conn2", "conn2->server-conn2"},
+		},
+		{
+			name: "tunnels cross connected with tracked interfaces are kept",
+			vppInterfaces: []*vppinterfaces.Interface{
+				vppInterface("server-conn1", vppinterfaces.Interface_MEMIF),
+				vppInterface("conn1", vppinterfaces.Interface_VXLAN_TUNNEL),
+			},
+			xconnectPairs: []*vppl2.XConnectPair{
+				xconnect("server-conn1", "conn1"),
+				xconnect("conn1", "server-conn1"),
+			},
+		},
+		{
+			name: "tunnels of others are left",
+			vppInterfaces: []*vppinterfaces.Interface{
+				vppInterface("tap0", vppinterfaces.Interface_TAP),
+				vppInterface("conn3", vppinterfaces.Interface_VXLAN_TUNNEL),
+				vppInterface("conn4", vppinterfaces.Interface_VXLAN_TUNNEL),
+			},
+			xconnectPairs: []*vppl2.XConnectPair{
+				xconnect("tap0", "conn3"),
+				xconnect("conn3", "tap0"),
+			},
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			vppagent := &testVppagent{dump: &configurator.Config{
+				VppConfig:   &vpp.ConfigData{Interfaces: testCase.vppInterfaces, XconnectPairs: testCase.xconnectPairs},
+				LinuxConfig: &linux.ConfigData{Interfaces: testCase.linuxInterfaces},
+			}}
+			report, err := reconcile.Run(context.Background(), vppagent, "", "", keep)
+			require.NoError(t, err)
+			require.Equal(t, testCase.wantVppInterfaces, report.VppInterfaces)
+			require.Equal(t, testCase.wantXconnectPairs, report.XconnectPairs)
+			require.Equal(t, testCase.wantLinuxInterfaces, report.LinuxInterfaces)
+
+			if testCase.wantVppInterfaces == nil && testCase.wantLinuxInterfaces == nil {
+				require.Nil(t, vppagent.deleted)
+				return
+			}
+			var deleted []string
+			for _, iface := range vppagent.deleted.GetVppConfig().GetInterfaces() {
+				deleted = append(deleted, iface.GetName())
+			}
+			require.Equal(t, testCase.wantVppInterfaces, deleted)
+			require.Len(t, vppagent.deleted.GetVppConfig().GetXconnectPairs(), len(testCase.wantXconnectPairs))
+			require.Len(t, vppagent.deleted.GetLinuxConfig().GetInterfaces(), len(testCase.wantLinuxInterfaces))
+		})
+	}
+}
+
+func TestRun_MemifSockets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reconcile")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	// The temporary directory of the previous instance, holding the socket of a tracked connection, and the memif
+	// socket directory
+	tmpDir := filepath.Join(dir, "forwarder-1")
+	memifSocketDir := filepath.Join(dir, "memif")
+	for _, path := range []string{
+		filepath.Join(tmpDir, "nsc", "conn1.memif.socket"),
+		filepath.Join(tmpDir, "nsc", "conn2.memif.socket"),
+		filepath.Join(tmpDir, "other", "conn2.memif.socket"),
+		filepath.Join(memifSocketDir, "seg1.memif.socket"),
+		filepath.Join(memifSocketDir, "conn3.memif.socket"),
+		filepath.Join(memifSocketDir, "other.sock"),
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, ioutil.WriteFile(path, nil, 0600))
+	}
+
+	keep := []*networkservice.Connection{{
+		Id:   "conn1",
+		Path: &networkservice.Path{PathSegments: []*networkservice.PathSegment{{Id: "seg1"}}},
+	}}
+	report, err := reconcile.Run(context.Background(), &testVppagent{}, filepath.Join(dir, "forwarder-*"), memifSocketDir, keep)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{
+		filepath.Join(tmpDir, "nsc", "conn2.memif.socket"),
+		filepath.Join(tmpDir, "other", "conn2.memif.socket"),
+		filepath.Join(memifSocketDir, "conn3.memif.socket"),
+	}, report.MemifSockets)
+	// The temporary directory still holds the socket of conn1
+	require.Empty(t, report.TmpDirs)
+	require.FileExists(t, filepath.Join(tmpDir, "nsc", "conn1.memif.socket"))
+	require.NoDirExists(t, filepath.Join(tmpDir, "other"))
+	require.FileExists(t, filepath.Join(memifSocketDir, "seg1.memif.socket"))
+	require.FileExists(t, filepath.Join(memifSocketDir, "other.sock"))
+
+	// Once conn1 is no longer tracked, the temporary directory goes
+	report, err = reconcile.Run(context.Background(), &testVppagent{}, filepath.Join(dir, "forwarder-*"), "", nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{tmpDir}, report.TmpDirs)
+	require.NoDirExists(t, tmpDir)
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registration
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Option - option for use with NewRegistrar(...)
+type Option func(r *Registrar)
+
+// WithExpirationPeriod - sets the period for which each registration is valid.  The registration is refreshed
+// well before it elapses.
+func WithExpirationPeriod(expirationPeriod time.Duration) Option {
+	return func(r *Registrar) {
+		r.expirationPeriod = expirationPeriod
+	}
+}
+
+// WithBackoff - sets the minimum and maximum delay between retries after a failed registration
+func WithBackoff(minBackoff, maxBackoff time.Duration) Option {
+	return func(r *Registrar) {
+		r.minBackoff = minBackoff
+		r.maxBackoff = maxBackoff
+	}
+}
+
+// WithClientConn - watches cc and re-registers immediately whenever the connection to the registry becomes
+// ready again after having been lost
+func WithClientConn(cc *grpc.ClientConn) Option {
+	return func(r *Registrar) {
+		r.cc = cc
+	}
+}
+
+// WithReconnectCh - re-registers immediately each time ch receives, for instance when a connection to another of
+// several registry endpoints has been established, which may not know of our registration
+func WithReconnectCh(ch <-chan struct{}) Option {
+	return func(r *Registrar) {
+		r.reconnectCh = ch
+	}
+}
+
+// WithStatusFunc - sets a function to be called with the result of every registration attempt
+func WithStatusFunc(statusFunc func(err error)) Option {
+	return func(r *Registrar) {
+		r.statusFunc = statusFunc
+	}
+}
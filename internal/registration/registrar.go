@@ -0,0 +1,238 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package registration provides a Registrar that keeps a NetworkServiceEndpoint registered with the registry,
+// refreshing it before it expires and re-registering it whenever the registry comes back after being lost
+package registration
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/networkservicemesh/api/pkg/api/registry"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/log"
+)
+
+const (
+	defaultExpirationPeriod = time.Minute
+	defaultMinBackoff       = time.Second
+	defaultMaxBackoff       = 30 * time.Second
+)
+
+// Registrar - keeps a NetworkServiceEndpoint registered with the registry
+type Registrar struct {
+	ctx              context.Context
+	cancel           context.CancelFunc
+	client           registry.NetworkServiceEndpointRegistryClient
+	expirationPeriod time.Duration
+	minBackoff       time.Duration
+	maxBackoff       time.Duration
+	cc               *grpc.ClientConn
+	reconnectCh      <-chan struct{}
+	statusFunc       func(err error)
+
+	// registerNowCh - signals the refresh loop to re-register without waiting for the next refresh
+	registerNowCh chan struct{}
+	// refreshDone - closed once the refresh loop has exited
+	refreshDone chan struct{}
+
+	mu         sync.Mutex
+	nse        *registry.NetworkServiceEndpoint
+	registered *registry.NetworkServiceEndpoint
+}
+
+// NewRegistrar - creates a new Registrar for nse using client
+//                ctx - context for the lifecycle of the Registrar.  When it is done, refreshing stops.
+func NewRegistrar(ctx context.Context, client registry.NetworkServiceEndpointRegistryClient, nse *registry.NetworkServiceEndpoint, options ...Option) *Registrar {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &Registrar{
+		ctx:              ctx,
+		cancel:           cancel,
+		client:           client,
+		nse:              nse,
+		expirationPeriod: defaultExpirationPeriod,
+		minBackoff:       defaultMinBackoff,
+		maxBackoff:       defaultMaxBackoff,
+		statusFunc:       func(error) {},
+		registerNowCh:    make(chan struct{}, 1),
+		refreshDone:      make(chan struct{}),
+	}
+	for _, opt := range options {
+		opt(r)
+	}
+	return r
+}
+
+// Register - registers the NetworkServiceEndpoint and, if that succeeds, keeps it registered in the background
+//            until the Registrar's ctx is done
+func (r *Registrar) Register() error {
+	if err := r.register(r.ctx); err != nil {
+		close(r.refreshDone)
+		return err
+	}
+	if r.cc != nil {
+		go r.watchConnectivity()
+	}
+	if r.reconnectCh != nil {
+		go r.watchReconnects()
+	}
+	go r.refreshLoop()
+	return nil
+}
+
+// NetworkServiceEndpoint - returns the NetworkServiceEndpoint as last accepted by the registry, or nil if it has never
+//                          been registered
+func (r *Registrar) NetworkServiceEndpoint() *registry.NetworkServiceEndpoint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.registered == nil {
+		return nil
+	}
+	return proto.Clone(r.registered).(*registry.NetworkServiceEndpoint)
+}
+
+// Unregister - stops refreshing the registration and unregisters the NetworkServiceEndpoint from the registry.  Once
+//              it has succeeded, calling it again does nothing.
+func (r *Registrar) Unregister(ctx context.Context) error {
+	r.cancel()
+	if r.NetworkServiceEndpoint() == nil {
+		return nil
+	}
+	select {
+	case <-r.refreshDone:
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "timed out waiting for the registration refresh to stop")
+	}
+	nse := r.NetworkServiceEndpoint()
+	if _, err := r.client.Unregister(ctx, nse); err != nil {
+		return errors.Wrapf(err, "failed to unregister %s from the registry", nse.GetName())
+	}
+	r.mu.Lock()
+	r.registered = nil
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *Registrar) register(ctx context.Context) error {
+	r.mu.Lock()
+	nse := proto.Clone(r.nse).(*registry.NetworkServiceEndpoint)
+	r.mu.Unlock()
+
+	expirationTime, err := ptypes.TimestampProto(time.Now().Add(r.expirationPeriod))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	nse.ExpirationTime = expirationTime
+
+	registered, err := r.client.Register(ctx, nse)
+	r.statusFunc(err)
+	if err != nil {
+		return errors.Wrapf(err, "failed to register %s with the registry", nse.GetName())
+	}
+
+	r.mu.Lock()
+	r.registered = registered
+	r.mu.Unlock()
+	return nil
+}
+
+// refreshAfter - returns how long to wait before refreshing the current registration: a third of its remaining
+//                lifetime, so that there is room for several retries before it expires
+func (r *Registrar) refreshAfter() time.Duration {
+	expirationTime, err := ptypes.Timestamp(r.NetworkServiceEndpoint().GetExpirationTime())
+	if err != nil {
+		return r.expirationPeriod / 3
+	}
+	return time.Until(expirationTime) / 3
+}
+
+func (r *Registrar) refreshLoop() {
+	defer close(r.refreshDone)
+	logEntry := log.Entry(r.ctx).WithField("registration", r.nse.GetName())
+	backoff := r.minBackoff
+	timer := time.NewTimer(r.refreshAfter())
+	defer timer.Stop()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-r.registerNowCh:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			logEntry.Infof("re-registering immediately")
+		case <-timer.C:
+		}
+		if err := r.register(r.ctx); err != nil {
+			if r.ctx.Err() != nil {
+				return
+			}
+			logEntry.Warnf("%+v, retrying in %s", err, backoff)
+			timer.Reset(backoff)
+			backoff *= 2
+			if backoff > r.maxBackoff {
+				backoff = r.maxBackoff
+			}
+			continue
+		}
+		backoff = r.minBackoff
+		refreshAfter := r.refreshAfter()
+		logEntry.Debugf("registration refreshed, next refresh in %s", refreshAfter)
+		timer.Reset(refreshAfter)
+	}
+}
+
+// watchConnectivity - triggers an immediate re-registration each time r.cc becomes Ready after having been in any
+//                     other state, as the registry may have lost our registration while we were disconnected
+func (r *Registrar) watchConnectivity() {
+	state := r.cc.GetState()
+	for r.cc.WaitForStateChange(r.ctx, state) {
+		newState := r.cc.GetState()
+		if newState == connectivity.Ready && state != connectivity.Ready {
+			log.Entry(r.ctx).WithField("registration", r.nse.GetName()).Infof("connection to registry re-established")
+			r.RegisterNow()
+		}
+		state = newState
+	}
+}
+
+// watchReconnects - triggers an immediate re-registration each time r.reconnectCh receives
+func (r *Registrar) watchReconnects() {
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-r.reconnectCh:
+			log.Entry(r.ctx).WithField("registration", r.nse.GetName()).Infof("connected to another registry endpoint")
+			r.RegisterNow()
+		}
+	}
+}
+
+// RegisterNow - triggers an immediate re-registration in the background
+func (r *Registrar) RegisterNow() {
+	select {
+	case r.registerNowCh <- struct{}{}:
+	default:
+	}
+}
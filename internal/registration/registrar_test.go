@@ -0,0 +1,319 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registration_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/networkservicemesh/api/pkg/api/registry"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/registration"
+)
+
+// registerCall - a Register call received by fakeRegistry
+type registerCall struct {
+	nse  *registry.NetworkServiceEndpoint
+	time time.Time
+	err  error
+}
+
+// fakeRegistry - accepts registrations as is, apart from the number of failures set, and records the calls it gets
+type fakeRegistry struct {
+	registry.NetworkServiceEndpointRegistryClient
+	calls chan registerCall
+
+	mu           sync.Mutex
+	failures     int
+	block        chan struct{}
+	events       []string
+	unregistered []*registry.NetworkServiceEndpoint
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{calls: make(chan registerCall, 100)}
+}
+
+func (f *fakeRegistry) Register(_ context.Context, nse *registry.NetworkServiceEndpoint, _ ...grpc.CallOption) (*registry.NetworkServiceEndpoint, error) {
+	f.mu.Lock()
+	block := f.block
+	var err error
+	if f.failures > 0 {
+		f.failures--
+		err = errors.New("registry unavailable")
+	}
+	f.events = append(f.events, "register")
+	f.mu.Unlock()
+
+	if block != nil {
+		<-block
+	}
+	f.calls <- registerCall{nse: nse, time: time.Now(), err: err}
+	if err != nil {
+		return nil, err
+	}
+	f.event("registered")
+	return nse, nil
+}
+
+func (f *fakeRegistry) Unregister(_ context.Context, nse *registry.NetworkServiceEndpoint, _ ...grpc.CallOption) (*empty.Empty, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, "unregister")
+	f.unregistered = append(f.unregistered, nse)
+	return &empty.Empty{}, nil
+}
+
+func (f *fakeRegistry) event(event string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, event)
+}
+
+func (f *fakeRegistry) next(t *testing.T) registerCall {
+	select {
+	case call := <-f.calls:
+		return call
+	case <-time.After(5 * time.Second):
+		t.Fatal("no registration")
+		return registerCall{}
+	}
+}
+
+func TestRegistrar_Refresh(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := newFakeRegistry()
+	registrar := registration.NewRegistrar(ctx, client, &registry.NetworkServiceEndpoint{Name: "forwarder"},
+		registration.WithExpirationPeriod(600*time.Millisecond))
+	start := time.Now()
+	require.NoError(t, registrar.Register())
+	first := client.next(t)
+	require.Equal(t, "forwarder", registrar.NetworkServiceEndpoint().GetName())
+
+	// Each registration expires after the expiration period
+	expirationTime, err := ptypes.Timestamp(first.nse.GetExpirationTime())
+	require.NoError(t, err)
+	require.WithinDuration(t, start.Add(600*time.Millisecond), expirationTime, 100*time.Millisecond)
+
+	// and is refreshed after a third of its remaining lifetime
+	second := client.next(t)
+	require.NoError(t, second.err)
+	require.GreaterOrEqual(t, int64(second.time.Sub(first.time)), int64(150*time.Millisecond))
+	require.Less(t, int64(second.time.Sub(first.time)), int64(400*time.Millisecond))
+}
+
+func TestRegistrar_Backoff(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := newFakeRegistry()
+	var mu sync.Mutex
+	var statuses []error
+	registrar := registration.NewRegistrar(ctx, client, &registry.NetworkServiceEndpoint{Name: "forwarder"},
+		registration.WithExpirationPeriod(300*time.Millisecond),
+		registration.WithBackoff(50*time.Millisecond, 60*time.Millisecond),
+		registration.WithStatusFunc(func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			statuses = append(statuses, err)
+		}),
+	)
+	require.NoError(t, registrar.Register())
+	client.next(t)
+
+	// The refreshes fail three times, and are retried after a backoff doubling up to its maximum
+	client.mu.Lock()
+	client.failures = 3
+	client.mu.Unlock()
+	var calls []registerCall
+	for i := 0; i < 4; i++ {
+		calls = append(calls, client.next(t))
+	}
+	for i, call := range calls {
+		require.Equal(t, i < 3, call.err != nil)
+	}
+	require.GreaterOrEqual(t, int64(calls[1].time.Sub(calls[0].time)), int64(50*time.Millisecond))
+	require.GreaterOrEqual(t, int64(calls[2].time.Sub(calls[1].time)), int64(60*time.Millisecond))
+	require.GreaterOrEqual(t, int64(calls[3].time.Sub(calls[2].time)), int64(60*time.Millisecond))
+	require.Less(t, int64(calls[3].time.Sub(calls[2].time)), int64(200*time.Millisecond))
+
+	// Every attempt is reported
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(statuses) >= 5
+	}, time.Second, 10*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	require.NoError(t, statuses[0])
+	for _, err := range statuses[1:4] {
+		require.Error(t, err)
+	}
+	require.NoError(t, statuses[4])
+}
+
+func TestRegistrar_Reconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := newFakeRegistry()
+	reconnectCh := make(chan struct{})
+	registrar := registration.NewRegistrar(ctx, client, &registry.NetworkServiceEndpoint{Name: "forwarder"},
+		registration.WithReconnectCh(reconnectCh))
+	require.NoError(t, registrar.Register())
+	client.next(t)
+
+	// Connecting to another registry endpoint re-registers long before the next refresh
+	reconnectCh <- struct{}{}
+	require.NoError(t, client.next(t).err)
+
+	registrar.RegisterNow()
+	require.NoError(t, client.next(t).err)
+}
+
+func TestRegistrar_ClientConn(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "registration")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	socket := filepath.Join(dir, "registry.sock")
+	serve := func() *grpc.Server {
+		listener, listenErr := net.Listen("unix", socket)
+		require.NoError(t, listenErr)
+		server := grpc.NewServer()
+		go func() { _ = server.Serve(listener) }()
+		return server
+	}
+	server := serve()
+	cc, err := grpc.DialContext(ctx, "unix:"+socket, grpc.WithInsecure(), grpc.WithBlock(),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoff.Config{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}}))
+	require.NoError(t, err)
+	defer func() { _ = cc.Close() }()
+
+	client := newFakeRegistry()
+	registrar := registration.NewRegistrar(ctx, client, &registry.NetworkServiceEndpoint{Name: "forwarder"},
+		registration.WithClientConn(cc))
+	require.NoError(t, registrar.Register())
+	client.next(t)
+
+	// The registry coming back may have lost the registration
+	server.Stop()
+	server = serve()
+	defer server.Stop()
+	require.NoError(t, client.next(t).err)
+}
+
+func TestRegistrar_Unregister(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := newFakeRegistry()
+	registrar := registration.NewRegistrar(ctx, client, &registry.NetworkServiceEndpoint{Name: "forwarder"},
+		registration.WithExpirationPeriod(300*time.Millisecond))
+	require.NoError(t, registrar.Register())
+	client.next(t)
+
+	// Unregistering waits for a refresh in progress, so that it does not register us again afterwards
+	block := make(chan struct{})
+	client.mu.Lock()
+	client.block = block
+	client.mu.Unlock()
+	require.Eventually(t, func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return len(client.events) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	unregistered := make(chan error, 1)
+	go func() { unregistered <- registrar.Unregister(ctx) }()
+	select {
+	case <-unregistered:
+		t.Fatal("unregistered during a refresh")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(block)
+	require.NoError(t, <-unregistered)
+	client.mu.Lock()
+	require.Equal(t, []string{"register", "registered", "register", "registered", "unregister"}, client.events)
+	require.Len(t, client.unregistered, 1)
+	require.Equal(t, "forwarder", client.unregistered[0].GetName())
+	client.mu.Unlock()
+	require.Nil(t, registrar.NetworkServiceEndpoint())
+
+	// Once unregistered, there is nothing left to do
+	require.NoError(t, registrar.Unregister(ctx))
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	require.Len(t, client.unregistered, 1)
+}
+
+func TestRegistrar_Unregister_Timeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := newFakeRegistry()
+	registrar := registration.NewRegistrar(ctx, client, &registry.NetworkServiceEndpoint{Name: "forwarder"},
+		registration.WithExpirationPeriod(300*time.Millisecond))
+	require.NoError(t, registrar.Register())
+	client.next(t)
+
+	block := make(chan struct{})
+	defer close(block)
+	client.mu.Lock()
+	client.block = block
+	client.mu.Unlock()
+	require.Eventually(t, func() bool {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return len(client.events) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// A refresh stuck past the deadline fails unregistering, without unregistering
+	unregisterCtx, unregisterCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer unregisterCancel()
+	require.Error(t, registrar.Unregister(unregisterCtx))
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	require.Empty(t, client.unregistered)
+}
+
+func TestRegistrar_Register_Failure(t *testing.T) {
+	client := newFakeRegistry()
+	client.failures = 1
+	registrar := registration.NewRegistrar(context.Background(), client, &registry.NetworkServiceEndpoint{Name: "forwarder"})
+	require.Error(t, registrar.Register())
+	require.Nil(t, registrar.NetworkServiceEndpoint())
+
+	// Nothing was registered, so there is nothing to unregister
+	require.NoError(t, registrar.Unregister(context.Background()))
+	require.Empty(t, client.unregistered)
+}
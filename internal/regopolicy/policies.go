@@ -0,0 +1,120 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package regopolicy authorizes NetworkService Requests with Rego policy files, which are reloaded whenever they
+// change
+package regopolicy
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/log"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/filewatch"
+)
+
+// rule - the rule each policy must define, which must evaluate to true for a Request to be allowed
+const rule = "allow"
+
+// Policies - a set of Rego policies, all of which must allow a Request for it to be allowed
+type Policies struct {
+	paths []string
+
+	mu      sync.RWMutex
+	queries []*query
+}
+
+type query struct {
+	path     string
+	prepared rego.PreparedEvalQuery
+}
+
+// Load - returns the Policies in the Rego files at paths.  Each of them must define an allow rule, evaluated with the
+// input described in the README.
+func Load(ctx context.Context, paths ...string) (*Policies, error) {
+	p := &Policies{paths: paths}
+	if err := p.reload(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Watch - reloads p whenever one of its files changes, until ctx is done.  Should the changed files fail to load,
+// the previous policies stay in force.
+func (p *Policies) Watch(ctx context.Context) error {
+	return filewatch.Watch(ctx, "policy", func() {
+		if err := p.reload(ctx); err != nil {
+			log.Entry(ctx).Errorf("keeping previous policies: %+v", err)
+			return
+		}
+		log.Entry(ctx).Infof("reloaded policies %v", p.paths)
+	}, p.paths...)
+}
+
+// Check - returns a PermissionDenied error unless every policy allows a Request with input
+func (p *Policies) Check(ctx context.Context, input interface{}) error {
+	p.mu.RLock()
+	queries := p.queries
+	p.mu.RUnlock()
+	for _, q := range queries {
+		rs, err := q.prepared.Eval(ctx, rego.EvalInput(input))
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to evaluate policy %s: %s", q.path, err)
+		}
+		if len(rs) == 0 || len(rs[0].Expressions) == 0 || rs[0].Expressions[0].Value != true {
+			return status.Errorf(codes.PermissionDenied, "not allowed by policy %s", q.path)
+		}
+	}
+	return nil
+}
+
+// reload - compiles all of the policy files, and only replaces the current policies if they all compiled
+func (p *Policies) reload(ctx context.Context) error {
+	queries := make([]*query, 0, len(p.paths))
+	for _, path := range p.paths {
+		source, err := ioutil.ReadFile(path) // #nosec
+		if err != nil {
+			return errors.Wrapf(err, "failed to read policy %s", path)
+		}
+		module, err := ast.ParseModule(path, string(source))
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse policy %s", path)
+		}
+		if module == nil {
+			return errors.Errorf("policy %s is empty", path)
+		}
+		prepared, err := rego.New(
+			rego.Query(module.Package.Path.String()+"."+rule),
+			rego.Module(path, string(source)),
+		).PrepareForEval(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "failed to compile policy %s", path)
+		}
+		queries = append(queries, &query{path: path, prepared: prepared})
+	}
+	p.mu.Lock()
+	p.queries = queries
+	p.mu.Unlock()
+	return nil
+}
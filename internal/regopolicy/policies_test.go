@@ -0,0 +1,177 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package regopolicy_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/networkservicemesh/sdk/pkg/networkservice/core/chain"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/conntrack"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/peertoken/peertokentest"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/regopolicy"
+)
+
+const clientID = "spiffe://example.org/ns/default/sa/nsc"
+
+const policy = `package forwarder
+
+allow {
+	input.network_service == "%s"
+	input.labels.app == "nsc"
+	input.path[0].name == "nsc"
+}
+`
+
+func writePolicy(t *testing.T, path, networkService string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(fmt.Sprintf(policy, networkService)), 0600))
+}
+
+// request - returns a Request of networkService forwarded by nsmgr, with a token signed by key
+func request(t *testing.T, nsmgr *peertokentest.NSMgr, networkService string, key *ecdsa.PrivateKey) *networkservice.NetworkServiceRequest {
+	return &networkservice.NetworkServiceRequest{
+		Connection: nsmgr.Forward(t, &networkservice.Connection{
+			Id:             "conn-id",
+			NetworkService: networkService,
+			Labels:         map[string]string{"app": "nsc"},
+		}, clientID, key),
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	nsmgr := peertokentest.New(t)
+	ctx, cancel := context.WithCancel(nsmgr.Context(context.Background()))
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "regopolicy")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "policy.rego")
+	writePolicy(t, path, "allowed")
+
+	policies, err := regopolicy.Load(ctx, path)
+	require.NoError(t, err)
+	server := chain.NewNetworkServiceServer(policies.NewServer())
+
+	_, err = server.Request(ctx, request(t, nsmgr, "allowed", nsmgr.Key))
+	require.NoError(t, err)
+	_, err = server.Request(ctx, request(t, nsmgr, "denied", nsmgr.Key))
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = server.Close(ctx, request(t, nsmgr, "denied", nsmgr.Key).GetConnection())
+	require.NoError(t, err)
+
+	require.NoError(t, policies.Watch(ctx))
+	writePolicy(t, path, "denied")
+	require.Eventually(t, func() bool {
+		_, requestErr := server.Request(ctx, request(t, nsmgr, "denied", nsmgr.Key))
+		return requestErr == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = server.Request(ctx, request(t, nsmgr, "allowed", nsmgr.Key))
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// An invalid policy keeps the previous one in force, until a valid one replaces it
+	require.NoError(t, ioutil.WriteFile(path, []byte("package"), 0600))
+	writePolicy(t, path, "allowed")
+	require.Eventually(t, func() bool {
+		_, requestErr := server.Request(ctx, request(t, nsmgr, "allowed", nsmgr.Key))
+		if requestErr != nil {
+			require.Equal(t, codes.PermissionDenied, status.Code(requestErr))
+		}
+		return requestErr == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = server.Request(ctx, request(t, nsmgr, "denied", nsmgr.Key))
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	_, err := regopolicy.Load(context.Background(), filepath.Join(os.TempDir(), "missing.rego"))
+	require.Error(t, err)
+}
+
+func TestPolicies_Replay(t *testing.T) {
+	nsmgr := peertokentest.New(t)
+	ctx, cancel := context.WithCancel(nsmgr.Context(context.Background()))
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "regopolicy")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "policy.rego")
+	writePolicy(t, path, "allowed")
+
+	policies, err := regopolicy.Load(ctx, path)
+	require.NoError(t, err)
+	require.NoError(t, policies.Watch(ctx))
+	tracker := conntrack.NewServer()
+	server := chain.NewNetworkServiceServer(tracker, policies.NewServer())
+
+	_, err = server.Request(ctx, request(t, nsmgr, "allowed", nsmgr.Key))
+	require.NoError(t, err)
+	writePolicy(t, path, "denied")
+	require.Eventually(t, func() bool {
+		_, requestErr := server.Request(ctx, request(t, nsmgr, "allowed", nsmgr.Key))
+		return status.Code(requestErr) == codes.PermissionDenied
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The connection is still tracked, as the denied Request did not Close it, and its replay is not evaluated
+	require.NoError(t, tracker.Replay(ctx, server))
+}
+
+func TestPolicies_Unverified(t *testing.T) {
+	nsmgr := peertokentest.New(t)
+	ctx, cancel := context.WithCancel(nsmgr.Context(context.Background()))
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "regopolicy")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "policy.rego")
+	writePolicy(t, path, "allowed")
+
+	policies, err := regopolicy.Load(ctx, path)
+	require.NoError(t, err)
+	server := chain.NewNetworkServiceServer(policies.NewServer())
+
+	// The claims of a path not signed by the peer cannot be trusted
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, err = server.Request(ctx, request(t, nsmgr, "allowed", otherKey))
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = server.Request(context.Background(), request(t, nsmgr, "allowed", nsmgr.Key))
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Nor are they needed without policies
+	policies, err = regopolicy.Load(ctx)
+	require.NoError(t, err)
+	server = chain.NewNetworkServiceServer(policies.NewServer())
+	_, err = server.Request(context.Background(), request(t, nsmgr, "allowed", otherKey))
+	require.NoError(t, err)
+}
@@ -0,0 +1,159 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package regopolicy
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/pkg/errors"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/networkservicemesh/sdk/pkg/networkservice/core/next"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/conntrack"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/peertoken"
+)
+
+type policyServer struct {
+	policies *Policies
+}
+
+// input - the input document the policies are evaluated with
+type input struct {
+	// Connection - the requested connection, in its protobuf JSON representation
+	Connection           json.RawMessage   `json:"connection"`
+	NetworkService       string            `json:"network_service"`
+	Labels               map[string]string `json:"labels"`
+	Mechanism            string            `json:"mechanism"`
+	MechanismPreferences []string          `json:"mechanism_preferences"`
+	// Path - the path segments up to the forwarder's client, along with the claims of their tokens.  Only the token
+	// of the client, the mTLS peer, is verified: those of the segments before it are as vouched for by the peer.
+	Path []*segment `json:"path"`
+	// PeerSpiffeID - SPIFFE ID of the mTLS peer calling the forwarder
+	PeerSpiffeID string `json:"peer_spiffe_id"`
+}
+
+type segment struct {
+	Name   string                 `json:"name"`
+	ID     string                 `json:"id"`
+	Claims map[string]interface{} `json:"claims"`
+}
+
+// NewServer - returns a NetworkServiceServer chain element rejecting the Requests not allowed by p, and those whose
+// path the mTLS peer did not sign, as the policies are evaluated on its claims.  Closes are always allowed, so that
+// connections established under a previous policy can still be closed, and so are the replays and restores of
+// tracked connections.
+func (p *Policies) NewServer() networkservice.NetworkServiceServer {
+	return &policyServer{policies: p}
+}
+
+func (s *policyServer) Request(ctx context.Context, request *networkservice.NetworkServiceRequest) (*networkservice.Connection, error) {
+	// A replay or restore carries no peer, and replays a connection the policies allowed when it was Requested
+	if conntrack.IsReplay(ctx) {
+		return next.Server(ctx).Request(ctx, request)
+	}
+	// Without policies, there is nothing to evaluate the path for
+	if len(s.policies.paths) == 0 {
+		return next.Server(ctx).Request(ctx, request)
+	}
+	if err := peertoken.Verify(ctx, request.GetConnection()); err != nil {
+		return nil, status.Errorf(codes.PermissionDenied, "cannot verify the path to evaluate the policies: %s", err)
+	}
+	in, err := newInput(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.policies.Check(ctx, in); err != nil {
+		return nil, err
+	}
+	return next.Server(ctx).Request(ctx, request)
+}
+
+func (s *policyServer) Close(ctx context.Context, conn *networkservice.Connection) (*empty.Empty, error) {
+	return next.Server(ctx).Close(ctx, conn)
+}
+
+// newInput - returns the input document for request, as plain JSON values
+func newInput(ctx context.Context, request *networkservice.NetworkServiceRequest) (interface{}, error) {
+	conn := request.GetConnection()
+	marshalled, err := (&jsonpb.Marshaler{OrigName: true}).MarshalToString(conn)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal connection")
+	}
+	in := &input{
+		Connection:     json.RawMessage(marshalled),
+		NetworkService: conn.GetNetworkService(),
+		Labels:         conn.GetLabels(),
+		Mechanism:      conn.GetMechanism().GetType(),
+		PeerSpiffeID:   peerSpiffeID(ctx),
+	}
+	for _, mechanism := range request.GetMechanismPreferences() {
+		in.MechanismPreferences = append(in.MechanismPreferences, mechanism.GetType())
+	}
+	segments := conn.GetPath().GetPathSegments()
+	for i := 0; i < len(segments) && i <= int(conn.GetPath().GetIndex()); i++ {
+		in.Path = append(in.Path, &segment{
+			Name:   segments[i].GetName(),
+			ID:     segments[i].GetId(),
+			Claims: claims(segments[i].GetToken()),
+		})
+	}
+	// Rego only takes plain JSON values as input
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var rv interface{}
+	if err = json.Unmarshal(b, &rv); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return rv, nil
+}
+
+// claims - returns the claims of token, without verifying it, which is up to peertoken.Verify(...)
+func claims(token string) map[string]interface{} {
+	mapClaims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, mapClaims); err != nil {
+		return map[string]interface{}{}
+	}
+	return mapClaims
+}
+
+func peerSpiffeID(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return ""
+	}
+	id, err := x509svid.IDFromCert(tlsInfo.State.PeerCertificates[0])
+	if err != nil {
+		return ""
+	}
+	return id.String()
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package spiffepattern matches SPIFFE IDs against the patterns the forwarder's options accept
+package spiffepattern
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+)
+
+// prefixSuffix - suffix of a pattern matching all the SPIFFE IDs whose path starts with the pattern's path
+const prefixSuffix = "/*"
+
+type prefix struct {
+	prefix string
+	index  int
+}
+
+// Set - SPIFFE ID patterns, each of which is either:
+//       - a SPIFFE ID, e.g. spiffe://example.org/ns/nsm-system/sa/nsmgr, matching exactly that ID
+//       - a SPIFFE ID followed by /*, e.g. spiffe://example.org/ns/nsm-system/*, matching the IDs whose path starts
+//         with that of the given ID
+//       - a trust domain as a SPIFFE ID without a path, e.g. spiffe://example.org, matching any ID in it
+type Set struct {
+	patterns     []string
+	ids          map[string]int
+	prefixes     []*prefix
+	trustDomains map[string]int
+}
+
+// New - returns an empty Set
+func New() *Set {
+	return &Set{
+		ids:          make(map[string]int),
+		trustDomains: make(map[string]int),
+	}
+}
+
+// Add - adds pattern to s, and returns its index
+func (s *Set) Add(pattern string) (int, error) {
+	isPrefix := strings.HasSuffix(pattern, prefixSuffix)
+	id, err := spiffeid.FromString(strings.TrimSuffix(pattern, prefixSuffix))
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid SPIFFE ID pattern %q", pattern)
+	}
+	index := len(s.patterns)
+	switch {
+	case isPrefix:
+		s.patterns = append(s.patterns, id.String()+prefixSuffix)
+		s.prefixes = append(s.prefixes, &prefix{prefix: id.String() + "/", index: index})
+		// The longest, most specific, prefix matching wins
+		sort.SliceStable(s.prefixes, func(i, j int) bool { return len(s.prefixes[i].prefix) > len(s.prefixes[j].prefix) })
+	case id.Path() == "":
+		s.patterns = append(s.patterns, id.TrustDomain().IDString())
+		s.trustDomains[id.TrustDomain().String()] = index
+	default:
+		s.patterns = append(s.patterns, id.String())
+		s.ids[id.String()] = index
+	}
+	return index, nil
+}
+
+// Len - returns the number of patterns in s
+func (s *Set) Len() int {
+	return len(s.patterns)
+}
+
+// Pattern - returns the pattern with index, in its canonical form
+func (s *Set) Pattern(index int) string {
+	return s.patterns[index]
+}
+
+// Match - returns the index of the most specific pattern matching id, if any: an exact ID, then the longest prefix,
+// then a trust domain
+func (s *Set) Match(id spiffeid.ID) (index int, ok bool) {
+	if index, ok := s.ids[id.String()]; ok {
+		return index, true
+	}
+	for _, p := range s.prefixes {
+		if strings.HasPrefix(id.String(), p.prefix) {
+			return p.index, true
+		}
+	}
+	if index, ok := s.trustDomains[id.TrustDomain().String()]; ok {
+		return index, true
+	}
+	return 0, false
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spiffepattern_test
+
+import (
+	"testing"
+
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/spiffepattern"
+)
+
+func TestSet(t *testing.T) {
+	set := spiffepattern.New()
+	for i, pattern := range []string{
+		"spiffe://example.org",
+		"spiffe://example.org/ns/default/*",
+		"spiffe://example.org/ns/default/sa/batch/*",
+		"spiffe://example.org/ns/default/sa/web",
+	} {
+		index, err := set.Add(pattern)
+		require.NoError(t, err)
+		require.Equal(t, i, index)
+		require.Equal(t, pattern, set.Pattern(index))
+	}
+	require.Equal(t, 4, set.Len())
+
+	for id, expected := range map[string]int{
+		"spiffe://example.org/ns/default/sa/web":       3,
+		"spiffe://example.org/ns/default/sa/batch/job": 2,
+		"spiffe://example.org/ns/default/sa/batch":     1,
+		"spiffe://example.org/ns/default/sa/db":        1,
+		"spiffe://example.org/ns/default":              0,
+		"spiffe://example.org/ns/other/sa/db":          0,
+	} {
+		index, ok := set.Match(spiffeid.RequireFromString(id))
+		require.True(t, ok, id)
+		require.Equal(t, expected, index, id)
+	}
+	_, ok := set.Match(spiffeid.RequireFromString("spiffe://other.org/ns/default/sa/web"))
+	require.False(t, ok)
+}
+
+func TestSet_Invalid(t *testing.T) {
+	for _, pattern := range []string{"", "example.org/ns", "/*"} {
+		_, err := spiffepattern.New().Add(pattern)
+		require.Error(t, err, pattern)
+	}
+}
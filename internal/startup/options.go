@@ -0,0 +1,35 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package startup
+
+// Option - option for use with New(...)
+type Option func(s *Sequence)
+
+// WithBeginFunc - sets a function called with the name of each phase as it begins, and with "" once the startup has
+// ended, such as metrics.Metrics.StartPhase
+func WithBeginFunc(beginFunc func(name string)) Option {
+	return func(s *Sequence) {
+		s.beginFunc = beginFunc
+	}
+}
+
+// WithExitFunc - sets the function exiting with the exit code of a failed phase, os.Exit by default
+func WithExitFunc(exitFunc func(code int)) Option {
+	return func(s *Sequence) {
+		s.exitFunc = exitFunc
+	}
+}
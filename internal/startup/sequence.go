@@ -0,0 +1,230 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package startup runs the forwarder through its startup phases, each under a timeout, and reports which phase failed
+// or got stuck, along with what it was waiting on, before exiting with an exit code specific to that phase
+package startup
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/log"
+)
+
+// Phase - a phase of the startup
+type Phase struct {
+	// Name - short name of the phase, as used in metrics and failure reports
+	Name string
+	// Description - what the phase does, as logged when it begins
+	Description string
+	// ExitCode - exit code of the forwarder when the phase fails or times out
+	ExitCode int
+	// Timeout - maximum duration of the phase, 0 for none
+	Timeout time.Duration
+	// WaitingOn - what the phase may block on, such as a socket path or a URL
+	WaitingOn string
+}
+
+// Report - the report of a failed startup phase
+type Report struct {
+	Phase       string `json:"phase"`
+	PhaseNumber int    `json:"phaseNumber"`
+	WaitingOn   string `json:"waitingOn,omitempty"`
+	Error       string `json:"error"`
+	TimedOut    bool   `json:"timedOut"`
+	// Timeout, Elapsed, SinceStart - durations as strings, e.g. "1m0s"
+	Timeout    string `json:"timeout,omitempty"`
+	Elapsed    string `json:"elapsed"`
+	SinceStart string `json:"sinceStart"`
+	ExitCode   int    `json:"exitCode"`
+}
+
+// Sequence - the startup phases, run one after the other
+type Sequence struct {
+	ctx        context.Context
+	started    time.Time
+	beginFunc  func(name string)
+	exitFunc   func(code int)
+	reportFile string
+
+	mu         sync.Mutex
+	number     int
+	phase      *Phase
+	phaseStart time.Time
+	timer      *time.Timer
+	ended      bool
+	cause      *cause
+}
+
+// cause - the failure in the background a phase fails on
+type cause struct {
+	err      error
+	exitCode int
+}
+
+// New - returns a Sequence without any phase begun yet
+func New(ctx context.Context, options ...Option) *Sequence {
+	s := &Sequence{
+		ctx:       ctx,
+		started:   time.Now(),
+		beginFunc: func(string) {},
+		exitFunc:  os.Exit,
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// SetReportFile - sets a file to write the Report of a failed phase to, as JSON, such as /dev/termination-log for
+// Kubernetes to show it as the termination message of the container.  As this typically comes from the config, it
+// may be set once the startup has begun.
+func (s *Sequence) SetReportFile(reportFile string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reportFile = reportFile
+}
+
+// Begin - ends the current phase, if any, and begins phase.  Should phase not end within its timeout, it fails.
+func (s *Sequence) Begin(phase *Phase) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopTimer()
+	s.number++
+	s.phase = phase
+	s.phaseStart = time.Now()
+	s.beginFunc(phase.Name)
+	log.Entry(s.ctx).Infof("executing phase %d: %s (time since start: %s)", s.number, phase.Description, time.Since(s.started))
+	if phase.Timeout > 0 {
+		number := s.number
+		s.timer = time.AfterFunc(phase.Timeout, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.number == number && !s.ended {
+				s.fail(errors.Errorf("timed out after %s", phase.Timeout), true)
+			}
+		})
+	}
+}
+
+// Fail - reports that the current phase failed with err, and exits with its exit code
+func (s *Sequence) Fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail(err, false)
+}
+
+// Check - fails the current phase with err, unless it is nil
+func (s *Sequence) Check(err error) {
+	if err != nil {
+		s.Fail(err)
+	}
+}
+
+// Background - returns a function to call with the error of what the current phase leaves running in the background,
+// such as a server, before cancelling the context the startup runs with.  Until the startup has ended, the phase
+// running then fails with that error and the exit code of the current phase, rather than on the cancelled context
+// with its own.  Only the first such error counts.
+func (s *Sequence) Background() func(err error) {
+	s.mu.Lock()
+	exitCode := s.phase.ExitCode
+	s.mu.Unlock()
+	return func(err error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err == nil || s.ended || s.cause != nil {
+			return
+		}
+		s.cause = &cause{err: err, exitCode: exitCode}
+	}
+}
+
+// End - ends the current phase, and with it the startup, unless a failure in the background makes it fail
+func (s *Sequence) End() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cause != nil {
+		s.fail(s.cause.err, false)
+		return
+	}
+	s.stopTimer()
+	s.ended = true
+	s.beginFunc("")
+	log.Entry(s.ctx).Infof("Startup completed in %v", time.Since(s.started))
+}
+
+// fail - must be called with s.mu locked
+func (s *Sequence) fail(err error, timedOut bool) {
+	s.stopTimer()
+	exitCode := s.phase.ExitCode
+	if s.cause != nil {
+		log.Entry(s.ctx).Warnf("startup phase %d (%s) failed on a failure in the background: %+v", s.number, s.phase.Name, err)
+		err, timedOut, exitCode = s.cause.err, false, s.cause.exitCode
+	}
+	report := &Report{
+		Phase:       s.phase.Name,
+		PhaseNumber: s.number,
+		WaitingOn:   s.phase.WaitingOn,
+		Error:       err.Error(),
+		TimedOut:    timedOut,
+		Elapsed:     time.Since(s.phaseStart).Round(time.Millisecond).String(),
+		SinceStart:  time.Since(s.started).Round(time.Millisecond).String(),
+		ExitCode:    exitCode,
+	}
+	if s.phase.Timeout > 0 {
+		report.Timeout = s.phase.Timeout.String()
+	}
+	log.Entry(s.ctx).WithFields(logrus.Fields{
+		"phase":       report.Phase,
+		"phaseNumber": report.PhaseNumber,
+		"waitingOn":   report.WaitingOn,
+		"timedOut":    report.TimedOut,
+		"timeout":     report.Timeout,
+		"elapsed":     report.Elapsed,
+		"sinceStart":  report.SinceStart,
+		"exitCode":    report.ExitCode,
+	}).Errorf("startup phase %d (%s) failed: %+v", s.number, s.phase.Name, err)
+	if s.reportFile != "" {
+		if writeErr := writeReport(s.reportFile, report); writeErr != nil {
+			log.Entry(s.ctx).Errorf("%+v", writeErr)
+		}
+	}
+	s.exitFunc(exitCode)
+}
+
+// stopTimer - must be called with s.mu locked
+func (s *Sequence) stopTimer() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+func writeReport(path string, report *Report) error {
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.Wrapf(ioutil.WriteFile(path, append(b, '\n'), 0600), "failed to write startup report to %s", path)
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package startup_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/startup"
+)
+
+func TestSequence_Timeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "startup")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	reportFile := filepath.Join(dir, "report.json")
+
+	exitCh := make(chan int, 1)
+	var begun []string
+	s := startup.New(context.Background(),
+		startup.WithExitFunc(func(code int) { exitCh <- code }),
+		startup.WithBeginFunc(func(name string) { begun = append(begun, name) }),
+	)
+	s.SetReportFile(reportFile)
+	s.Begin(&startup.Phase{Name: "config", ExitCode: 10, Timeout: time.Hour})
+	s.Begin(&startup.Phase{Name: "registration", ExitCode: 16, Timeout: 10 * time.Millisecond, WaitingOn: "tcp://registry:5002"})
+	select {
+	case code := <-exitCh:
+		require.Equal(t, 16, code)
+	case <-time.After(time.Second):
+		t.Fatal("phase did not time out")
+	}
+	require.Equal(t, []string{"config", "registration"}, begun)
+
+	b, err := ioutil.ReadFile(reportFile)
+	require.NoError(t, err)
+	report := &startup.Report{}
+	require.NoError(t, json.Unmarshal(b, report))
+	require.Equal(t, "registration", report.Phase)
+	require.Equal(t, 2, report.PhaseNumber)
+	require.Equal(t, "tcp://registry:5002", report.WaitingOn)
+	require.True(t, report.TimedOut)
+	require.Equal(t, "10ms", report.Timeout)
+	require.Equal(t, 16, report.ExitCode)
+}
+
+func TestSequence_Fail(t *testing.T) {
+	exitCh := make(chan int, 1)
+	s := startup.New(context.Background(), startup.WithExitFunc(func(code int) { exitCh <- code }))
+	s.Begin(&startup.Phase{Name: "svid", ExitCode: 12})
+	s.Fail(errors.New("no SVID"))
+	require.Equal(t, 12, <-exitCh)
+}
+
+func TestSequence_Background(t *testing.T) {
+	dir, err := ioutil.TempDir("", "startup")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	reportFile := filepath.Join(dir, "report.json")
+
+	exitCh := make(chan int, 1)
+	s := startup.New(context.Background(), startup.WithExitFunc(func(code int) { exitCh <- code }))
+	s.SetReportFile(reportFile)
+	s.Begin(&startup.Phase{Name: "vppagent", ExitCode: 11})
+	failed := s.Background()
+	s.Begin(&startup.Phase{Name: "svid", ExitCode: 12})
+
+	// The failure in the background comes first, whatever the running phase then fails with
+	failed(errors.New("vppagent exited"))
+	failed(errors.New("vppagent exited again"))
+	s.Fail(context.Canceled)
+	require.Equal(t, 11, <-exitCh)
+
+	b, err := ioutil.ReadFile(reportFile)
+	require.NoError(t, err)
+	report := &startup.Report{}
+	require.NoError(t, json.Unmarshal(b, report))
+	require.Equal(t, "svid", report.Phase)
+	require.Equal(t, "vppagent exited", report.Error)
+	require.Equal(t, 11, report.ExitCode)
+
+	// Nor does the startup end after it
+	s.End()
+	require.Equal(t, 11, <-exitCh)
+}
+
+func TestSequence_BackgroundAfterEnd(t *testing.T) {
+	exitCh := make(chan int, 1)
+	s := startup.New(context.Background(), startup.WithExitFunc(func(code int) { exitCh <- code }))
+	s.Begin(&startup.Phase{Name: "vppagent", ExitCode: 11})
+	failed := s.Background()
+	s.End()
+	failed(errors.New("vppagent exited"))
+	select {
+	case code := <-exitCh:
+		t.Fatalf("exited with %d after the startup ended", code)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSequence_End(t *testing.T) {
+	exitCh := make(chan int, 1)
+	var begun []string
+	s := startup.New(context.Background(),
+		startup.WithExitFunc(func(code int) { exitCh <- code }),
+		startup.WithBeginFunc(func(name string) { begun = append(begun, name) }),
+	)
+	s.Begin(&startup.Phase{Name: "registration", ExitCode: 16, Timeout: 10 * time.Millisecond})
+	s.End()
+	select {
+	case code := <-exitCh:
+		t.Fatalf("exited with %d after the startup ended", code)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// The end of the last phase is told too, for its duration to be recorded
+	require.Equal(t, []string{"registration", ""}, begun)
+}
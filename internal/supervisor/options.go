@@ -0,0 +1,58 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package supervisor
+
+import "time"
+
+const (
+	defaultMaxRestarts  = 5
+	defaultRestartDelay = time.Second
+	defaultStablePeriod = time.Minute
+)
+
+// Option - option for use with New(...)
+type Option func(s *Supervisor)
+
+// WithMaxRestarts - sets the number of consecutive failed restarts after which the Supervisor gives up
+func WithMaxRestarts(maxRestarts int) Option {
+	return func(s *Supervisor) {
+		s.maxRestarts = maxRestarts
+	}
+}
+
+// WithRestartDelay - sets the delay before each restart
+func WithRestartDelay(restartDelay time.Duration) Option {
+	return func(s *Supervisor) {
+		s.restartDelay = restartDelay
+	}
+}
+
+// WithStablePeriod - sets how long a vppagent must have been running before exiting for its restart to have
+// succeeded.  One that exits any sooner counts as another failed restart.
+func WithStablePeriod(stablePeriod time.Duration) Option {
+	return func(s *Supervisor) {
+		s.stablePeriod = stablePeriod
+	}
+}
+
+// WithStatusFunc - sets a function to be called with nil whenever a vppagent has been (re)started, and with the
+// error it exited with whenever it exits
+func WithStatusFunc(statusFunc func(err error)) Option {
+	return func(s *Supervisor) {
+		s.statusFunc = statusFunc
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package supervisor keeps a vppagent running, restarting it whenever it exits, and provides a
+// grpc.ClientConnInterface that always reaches the current vppagent instance
+package supervisor
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/log"
+)
+
+// StartFunc - starts a vppagent and dials it.  Cancelling ctx must stop the vppagent.  The returned error channel
+// receives any error from the lifecycle of the vppagent, and is closed once it has exited.
+type StartFunc func(ctx context.Context) (grpc.ClientConnInterface, <-chan error)
+
+// RestartHook - is called with the ClientConn to a freshly restarted vppagent, in order to restore its configuration
+type RestartHook func(ctx context.Context, cc grpc.ClientConnInterface) error
+
+// Supervisor - runs a vppagent, restarting it when it exits
+type Supervisor struct {
+	ctx          context.Context
+	start        StartFunc
+	maxRestarts  int
+	restartDelay time.Duration
+	stablePeriod time.Duration
+	statusFunc   func(err error)
+
+	mu    sync.Mutex
+	cc    grpc.ClientConnInterface
+	ready chan struct{}
+	hooks []RestartHook
+}
+
+type instance struct {
+	cc      grpc.ClientConnInterface
+	errCh   <-chan error
+	cancel  context.CancelFunc
+	started time.Time
+}
+
+// New - returns a Supervisor for vppagents started by start
+//       ctx - context for the lifecycle of the Supervisor.  When it is done, the vppagent is stopped and no longer
+//             restarted.
+func New(ctx context.Context, start StartFunc, options ...Option) *Supervisor {
+	s := &Supervisor{
+		ctx:          ctx,
+		start:        start,
+		maxRestarts:  defaultMaxRestarts,
+		restartDelay: defaultRestartDelay,
+		stablePeriod: defaultStablePeriod,
+		statusFunc:   func(error) {},
+		ready:        make(chan struct{}),
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// AddRestartHook - adds hook to the hooks run, in the order they were added, each time the vppagent is restarted.
+// The Supervisor already reaches the new vppagent when they run.  If any of them fails, the restart is considered
+// to have failed.
+func (s *Supervisor) AddRestartHook(hook RestartHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// Start - starts the vppagent.  The returned channel receives an error if the vppagent fails to start, or once it has
+// failed more than the permitted number of consecutive restarts, and is closed once the Supervisor is done.
+func (s *Supervisor) Start() <-chan error {
+	errCh := make(chan error, 1)
+	inst, err := s.startInstance()
+	if err != nil {
+		errCh <- err
+		close(errCh)
+		return errCh
+	}
+	s.setClientConn(inst.cc)
+	s.statusFunc(nil)
+	go s.supervise(inst, errCh)
+	return errCh
+}
+
+// Invoke - performs a unary RPC on the current vppagent, waiting for it to be (re)started if necessary
+func (s *Supervisor) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	cc, err := s.clientConn(ctx)
+	if err != nil {
+		return err
+	}
+	return cc.Invoke(ctx, method, args, reply, opts...)
+}
+
+// NewStream - begins a streaming RPC on the current vppagent, waiting for it to be (re)started if necessary
+func (s *Supervisor) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	cc, err := s.clientConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cc.NewStream(ctx, desc, method, opts...)
+}
+
+func (s *Supervisor) supervise(inst *instance, errCh chan<- error) {
+	defer close(errCh)
+	logEntry := log.Entry(s.ctx).WithField("supervisor", "vppagent")
+	failures := 0
+	for {
+		err := inst.wait()
+		s.unsetClientConn(inst.cc)
+		if s.ctx.Err() != nil {
+			return
+		}
+		logEntry.Errorf("vppagent exited: %+v", err)
+		s.statusFunc(err)
+		if time.Since(inst.started) < s.stablePeriod {
+			failures++
+		} else {
+			failures = 0
+		}
+		for {
+			if failures > s.maxRestarts {
+				errCh <- errors.Wrapf(err, "vppagent failed %d consecutive restarts", failures)
+				return
+			}
+			logEntry.Warnf("restarting vppagent in %s (consecutive failures: %d)", s.restartDelay, failures)
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(s.restartDelay):
+			}
+			if inst, err = s.restartInstance(); err == nil {
+				break
+			}
+			logEntry.Errorf("failed to restart vppagent: %+v", err)
+			failures++
+		}
+		logEntry.Infof("vppagent restarted")
+		s.statusFunc(nil)
+	}
+}
+
+func (s *Supervisor) startInstance() (*instance, error) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	cc, errCh := s.start(ctx)
+	inst := &instance{
+		cc:      cc,
+		errCh:   errCh,
+		cancel:  cancel,
+		started: time.Now(),
+	}
+	select {
+	case err, ok := <-errCh:
+		if !ok {
+			err = errors.New("vppagent exited")
+		}
+		_ = inst.wait()
+		closeClientConn(cc)
+		return nil, err
+	default:
+	}
+	return inst, nil
+}
+
+func (s *Supervisor) restartInstance() (*instance, error) {
+	inst, err := s.startInstance()
+	if err != nil {
+		return nil, err
+	}
+	s.setClientConn(inst.cc)
+	s.mu.Lock()
+	hooks := append([]RestartHook(nil), s.hooks...)
+	s.mu.Unlock()
+	for _, hook := range hooks {
+		if err := hook(s.ctx, inst.cc); err != nil {
+			s.unsetClientConn(inst.cc)
+			inst.stop()
+			return nil, err
+		}
+	}
+	return inst, nil
+}
+
+// clientConn - returns the ClientConn to the current vppagent, waiting until there is one or ctx is done
+func (s *Supervisor) clientConn(ctx context.Context) (grpc.ClientConnInterface, error) {
+	for {
+		s.mu.Lock()
+		cc, ready := s.cc, s.ready
+		s.mu.Unlock()
+		if cc != nil {
+			return cc, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "no vppagent available")
+		case <-s.ctx.Done():
+			return nil, errors.Wrap(s.ctx.Err(), "no vppagent available")
+		case <-ready:
+		}
+	}
+}
+
+func (s *Supervisor) setClientConn(cc grpc.ClientConnInterface) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cc = cc
+	close(s.ready)
+}
+
+// unsetClientConn - makes callers wait for the next vppagent instance, and closes cc
+func (s *Supervisor) unsetClientConn(cc grpc.ClientConnInterface) {
+	s.mu.Lock()
+	if s.cc == cc {
+		s.cc = nil
+		s.ready = make(chan struct{})
+	}
+	s.mu.Unlock()
+	closeClientConn(cc)
+}
+
+// wait - waits for the instance to report an error or exit, then stops it and waits for it to have exited completely
+func (i *instance) wait() error {
+	err, ok := <-i.errCh
+	i.cancel()
+	for range i.errCh {
+	}
+	if !ok {
+		return errors.New("vppagent exited")
+	}
+	return err
+}
+
+// stop - stops the instance, which may well be running fine, and waits for it to have exited completely
+func (i *instance) stop() {
+	i.cancel()
+	for range i.errCh {
+	}
+}
+
+func closeClientConn(cc grpc.ClientConnInterface) {
+	if closer, ok := cc.(io.Closer); ok {
+		_ = closer.Close()
+	}
+}
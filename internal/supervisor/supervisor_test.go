@@ -0,0 +1,155 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package supervisor_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/supervisor"
+)
+
+type fakeClientConn struct {
+	grpc.ClientConnInterface
+	id int32
+}
+
+// startFunc - returns a StartFunc whose vppagents exit with an error as soon as a value is sent on exitCh
+func startFunc(starts *int32, exitCh <-chan struct{}) supervisor.StartFunc {
+	return func(ctx context.Context) (grpc.ClientConnInterface, <-chan error) {
+		id := atomic.AddInt32(starts, 1)
+		errCh := make(chan error, 1)
+		go func() {
+			defer close(errCh)
+			select {
+			case <-ctx.Done():
+			case <-exitCh:
+				errCh <- errors.New("crashed")
+			}
+		}()
+		return &fakeClientConn{id: id}, errCh
+	}
+}
+
+func TestSupervisor_RestartsAndRunsHooks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var starts int32
+	exitCh := make(chan struct{})
+	s := supervisor.New(ctx, startFunc(&starts, exitCh), supervisor.WithRestartDelay(time.Millisecond))
+	hookCh := make(chan int32, 10)
+	s.AddRestartHook(func(ctx context.Context, cc grpc.ClientConnInterface) error {
+		hookCh <- cc.(*fakeClientConn).id
+		return nil
+	})
+	errCh := s.Start()
+
+	exitCh <- struct{}{}
+	select {
+	case id := <-hookCh:
+		require.Equal(t, int32(2), id)
+	case <-time.After(time.Second):
+		t.Fatal("vppagent was not restarted")
+	}
+
+	cancel()
+	_, ok := <-errCh
+	require.False(t, ok)
+}
+
+func TestSupervisor_GivesUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var starts int32
+	exitCh := make(chan struct{})
+	s := supervisor.New(ctx, startFunc(&starts, exitCh),
+		supervisor.WithRestartDelay(time.Millisecond),
+		supervisor.WithMaxRestarts(3),
+	)
+	errCh := s.Start()
+	close(exitCh)
+
+	select {
+	case err := <-errCh:
+		require.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("supervisor did not give up")
+	}
+	require.Equal(t, int32(4), atomic.LoadInt32(&starts))
+}
+
+func TestSupervisor_ReportsStatus(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var starts int32
+	exitCh := make(chan struct{})
+	statusCh := make(chan error, 10)
+	s := supervisor.New(ctx, startFunc(&starts, exitCh),
+		supervisor.WithRestartDelay(time.Millisecond),
+		supervisor.WithStatusFunc(func(err error) { statusCh <- err }),
+	)
+	errCh := s.Start()
+	require.NoError(t, <-statusCh)
+
+	exitCh <- struct{}{}
+	require.Error(t, <-statusCh)
+	select {
+	case err := <-statusCh:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("vppagent was not restarted")
+	}
+
+	cancel()
+	_, ok := <-errCh
+	require.False(t, ok)
+}
+
+func TestSupervisor_HookFails(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var starts int32
+	exitCh := make(chan struct{})
+	s := supervisor.New(ctx, startFunc(&starts, exitCh),
+		supervisor.WithRestartDelay(time.Millisecond),
+		supervisor.WithMaxRestarts(2),
+	)
+	s.AddRestartHook(func(ctx context.Context, cc grpc.ClientConnInterface) error {
+		return errors.New("failed to restore the configuration")
+	})
+	errCh := s.Start()
+
+	// The vppagents restarted run fine, yet count as failed restarts
+	exitCh <- struct{}{}
+	select {
+	case err := <-errCh:
+		require.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("supervisor did not give up")
+	}
+	require.Equal(t, int32(3), atomic.LoadInt32(&starts))
+}
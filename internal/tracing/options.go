@@ -0,0 +1,48 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+type tracingOptions struct {
+	serviceName   string
+	samplingRatio float64
+	otlpAddress   string
+}
+
+// Option - option for use with Init(...)
+type Option func(o *tracingOptions)
+
+// WithServiceName - sets the name of the service the spans are reported for
+func WithServiceName(serviceName string) Option {
+	return func(o *tracingOptions) {
+		o.serviceName = serviceName
+	}
+}
+
+// WithSamplingRatio - sets the ratio, from 0 to 1, of the traces started by the forwarder that are sampled.  Traces
+// started by a caller are sampled according to the decision of the caller.
+func WithSamplingRatio(samplingRatio float64) Option {
+	return func(o *tracingOptions) {
+		o.samplingRatio = samplingRatio
+	}
+}
+
+// WithOTLPAddress - sets the host:port of the OpenTelemetry collector that ExporterOTLP exports spans to
+func WithOTLPAddress(otlpAddress string) Option {
+	return func(o *tracingOptions) {
+		o.otlpAddress = otlpAddress
+	}
+}
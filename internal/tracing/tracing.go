@@ -0,0 +1,175 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing sets up the opentracing tracer used by the forwarder and the sdk, exporting spans to a Jaeger agent
+// or to an OpenTelemetry collector over OTLP, and tags the spans of the forwarder's API with what they are about
+package tracing
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/uber/jaeger-client-go"
+	jaegerconfig "github.com/uber/jaeger-client-go/config"
+	"go.opentelemetry.io/otel"
+	otbridge "go.opentelemetry.io/otel/bridge/opentracing"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"google.golang.org/grpc"
+
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+)
+
+const (
+	// ExporterJaeger - exports spans to the Jaeger agent configured by the JAEGER_* environment variables
+	ExporterJaeger = "jaeger"
+	// ExporterOTLP - exports spans to an OpenTelemetry collector over OTLP/gRPC
+	ExporterOTLP = "otlp"
+	// ExporterNone - disables tracing altogether
+	ExporterNone = "none"
+
+	// ConnectionIDTag, NetworkServiceTag, MechanismTag, MechanismPreferencesTag - tags of the spans of the
+	// NetworkService calls
+	ConnectionIDTag         = "connection.id"
+	NetworkServiceTag       = "connection.network_service"
+	MechanismTag            = "connection.mechanism"
+	MechanismPreferencesTag = "connection.mechanism_preferences"
+
+	// tracerEnabledEnv - environment variable the sdk checks for whether to trace at all
+	tracerEnabledEnv = "TRACER_ENABLED"
+	// instrumentationName - name of the OpenTelemetry tracer the opentracing spans are bridged to
+	instrumentationName = "github.com/networkservicemesh/cmd-forwarder-vppagent"
+	// shutdownTimeout - how long to wait for the last spans to be exported when closing
+	shutdownTimeout = 5 * time.Second
+)
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+// Init - sets up the global opentracing tracer to export spans with exporter: ExporterJaeger, ExporterOTLP or
+//        ExporterNone.  Closing the returned io.Closer flushes the spans not exported yet.
+func Init(ctx context.Context, exporter string, options ...Option) (io.Closer, error) {
+	o := &tracingOptions{
+		serviceName:   "forwarder",
+		samplingRatio: 1,
+		otlpAddress:   "localhost:55680",
+	}
+	for _, opt := range options {
+		opt(o)
+	}
+	switch exporter {
+	case ExporterJaeger:
+		return initJaeger(o)
+	case ExporterOTLP:
+		return initOTLP(ctx, o)
+	case ExporterNone:
+		if err := os.Setenv(tracerEnabledEnv, "false"); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return closerFunc(func() error { return nil }), nil
+	default:
+		return nil, errors.Errorf("unknown tracing exporter %q, expected %s, %s or %s",
+			exporter, ExporterJaeger, ExporterOTLP, ExporterNone)
+	}
+}
+
+func initJaeger(o *tracingOptions) (io.Closer, error) {
+	cfg, err := jaegerconfig.FromEnv()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid Jaeger configuration")
+	}
+	cfg.ServiceName = o.serviceName
+	// A child span is sampled along with its parent whatever the ratio
+	cfg.Sampler = &jaegerconfig.SamplerConfig{Type: jaeger.SamplerTypeProbabilistic, Param: o.samplingRatio}
+	tracer, closer, err := cfg.NewTracer()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create Jaeger tracer")
+	}
+	opentracing.SetGlobalTracer(tracer)
+	return closer, nil
+}
+
+func initOTLP(ctx context.Context, o *tracingOptions) (io.Closer, error) {
+	exporter, err := otlp.NewExporter(ctx, otlp.WithInsecure(), otlp.WithAddress(o.otlpAddress))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create OTLP exporter to %s", o.otlpAddress)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{
+			DefaultSampler: sdktrace.ParentBased(sdktrace.TraceIDRatioBased(o.samplingRatio)),
+		}),
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.ServiceNameKey.String(o.serviceName))),
+	)
+	bridgeTracer, wrapperProvider := otbridge.NewTracerPair(provider.Tracer(instrumentationName))
+	bridgeTracer.SetTextMapPropagator(propagation.TraceContext{})
+	otel.SetTracerProvider(wrapperProvider)
+	opentracing.SetGlobalTracer(bridgeTracer)
+	return closerFunc(func() error {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(shutdownCtx); err != nil {
+			return errors.WithStack(err)
+		}
+		return errors.WithStack(exporter.Shutdown(shutdownCtx))
+	}), nil
+}
+
+// ServerInterceptor - returns an interceptor tagging the span of each NetworkService call, as started by the
+//                     interceptors of spanhelper.WithTracing(), with the connection id, network service and mechanism
+//                     of its connection
+func ServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		span := opentracing.SpanFromContext(ctx)
+		if span == nil {
+			return resp, err
+		}
+		conn, ok := req.(*networkservice.Connection)
+		if request, isRequest := req.(*networkservice.NetworkServiceRequest); isRequest {
+			conn, ok = request.GetConnection(), true
+			var preferences []string
+			for _, mechanism := range request.GetMechanismPreferences() {
+				preferences = append(preferences, mechanism.GetType())
+			}
+			span.SetTag(MechanismPreferencesTag, strings.Join(preferences, ","))
+		}
+		if !ok {
+			return resp, err
+		}
+		// The connection returned has the mechanism chosen
+		if returned, isConn := resp.(*networkservice.Connection); isConn && returned != nil {
+			conn = returned
+		}
+		span.SetTag(ConnectionIDTag, conn.GetId())
+		span.SetTag(NetworkServiceTag, conn.GetNetworkService())
+		if mechanism := conn.GetMechanism().GetType(); mechanism != "" {
+			span.SetTag(MechanismTag, mechanism)
+		}
+		return resp, err
+	}
+}
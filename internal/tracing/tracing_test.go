@@ -0,0 +1,109 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/kernel"
+	"github.com/networkservicemesh/api/pkg/api/networkservice/mechanisms/memif"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/tracing"
+)
+
+func callWithSpan(t *testing.T, req, resp interface{}) map[string]interface{} {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("call")
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	_, err := tracing.ServerInterceptor()(ctx, req, &grpc.UnaryServerInfo{},
+		func(context.Context, interface{}) (interface{}, error) { return resp, nil })
+	require.NoError(t, err)
+	span.Finish()
+	require.Len(t, tracer.FinishedSpans(), 1)
+	return tracer.FinishedSpans()[0].Tags()
+}
+
+func TestServerInterceptor_Request(t *testing.T) {
+	request := &networkservice.NetworkServiceRequest{
+		Connection: &networkservice.Connection{Id: "conn-1", NetworkService: "ns-1"},
+		MechanismPreferences: []*networkservice.Mechanism{
+			{Type: memif.MECHANISM},
+			{Type: kernel.MECHANISM},
+		},
+	}
+	conn := &networkservice.Connection{
+		Id:             "conn-1",
+		NetworkService: "ns-1",
+		Mechanism:      &networkservice.Mechanism{Type: kernel.MECHANISM},
+	}
+	require.Equal(t, map[string]interface{}{
+		tracing.ConnectionIDTag:         "conn-1",
+		tracing.NetworkServiceTag:       "ns-1",
+		tracing.MechanismTag:            kernel.MECHANISM,
+		tracing.MechanismPreferencesTag: memif.MECHANISM + "," + kernel.MECHANISM,
+	}, callWithSpan(t, request, conn))
+}
+
+func TestServerInterceptor_FailedRequest(t *testing.T) {
+	request := &networkservice.NetworkServiceRequest{
+		Connection:           &networkservice.Connection{Id: "conn-1", NetworkService: "ns-1"},
+		MechanismPreferences: []*networkservice.Mechanism{{Type: memif.MECHANISM}},
+	}
+	var conn *networkservice.Connection
+	require.Equal(t, map[string]interface{}{
+		tracing.ConnectionIDTag:         "conn-1",
+		tracing.NetworkServiceTag:       "ns-1",
+		tracing.MechanismPreferencesTag: memif.MECHANISM,
+	}, callWithSpan(t, request, conn))
+}
+
+func TestServerInterceptor_Close(t *testing.T) {
+	conn := &networkservice.Connection{
+		Id:             "conn-1",
+		NetworkService: "ns-1",
+		Mechanism:      &networkservice.Mechanism{Type: memif.MECHANISM},
+	}
+	require.Equal(t, map[string]interface{}{
+		tracing.ConnectionIDTag:   "conn-1",
+		tracing.NetworkServiceTag: "ns-1",
+		tracing.MechanismTag:      memif.MECHANISM,
+	}, callWithSpan(t, conn, nil))
+}
+
+func TestServerInterceptor_OtherCalls(t *testing.T) {
+	require.Empty(t, callWithSpan(t, "not a connection", nil))
+}
+
+func TestInit_None(t *testing.T) {
+	defer func() { _ = os.Unsetenv("TRACER_ENABLED") }()
+	closer, err := tracing.Init(context.Background(), tracing.ExporterNone)
+	require.NoError(t, err)
+	require.Equal(t, "false", os.Getenv("TRACER_ENABLED"))
+	require.NoError(t, closer.Close())
+}
+
+func TestInit_Unknown(t *testing.T) {
+	_, err := tracing.Init(context.Background(), "zipkin")
+	require.Error(t, err)
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vppagentdial connects to an externally managed vppagent, rather than starting one along with the forwarder
+package vppagentdial
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/grpcutils"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/supervisor"
+)
+
+// StartFunc - returns a supervisor.StartFunc that dials the vppagent at u, waiting up to dialTimeout for it to become
+// ready, instead of starting one.  The vppagent is considered to have exited as soon as the connection to it fails,
+// or the dial times out, so that the supervisor dials it again and restores its configuration, and the connection is
+// then closed.  Cancelling the context only closes the connection, and never stops the vppagent itself.
+func StartFunc(u *url.URL, dialTimeout time.Duration, options ...grpc.DialOption) supervisor.StartFunc {
+	return func(ctx context.Context) (grpc.ClientConnInterface, <-chan error) {
+		errCh := make(chan error, 1)
+		dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+		defer cancel()
+		cc, err := grpc.DialContext(dialCtx, grpcutils.URLToTarget(u), append(options, grpc.WithBlock())...)
+		if err != nil {
+			errCh <- errors.Wrapf(err, "failed to dial vppagent at %s", u)
+			close(errCh)
+			return nil, errCh
+		}
+		go func() {
+			defer close(errCh)
+			defer func() { _ = cc.Close() }()
+			for state := cc.GetState(); ; state = cc.GetState() {
+				// Going idle or reconnecting, after a GOAWAY for instance, is no reason to restore the configuration
+				if state == connectivity.TransientFailure || state == connectivity.Shutdown {
+					errCh <- errors.Errorf("lost connection to vppagent at %s: %s", u, state)
+					return
+				}
+				if !cc.WaitForStateChange(ctx, state) {
+					return
+				}
+			}
+		}()
+		return cc, errCh
+	}
+}
+
+// TLSConfig - returns the TLS config for dialing a vppagent whose certificate is signed by the CA certificates in
+// caFile, or by those of the system if caFile is empty.  If certFile and keyFile are set, the forwarder
+// authenticates with the certificate and key in them.
+func TLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile) // #nosec
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read vppagent CA certificates %s", caFile)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no CA certificates in %s", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to load vppagent client certificate %s and key %s", certFile, keyFile)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vppagentdial_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/vppagentdial"
+)
+
+func TestStartFunc(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "vppagentdial")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	socket := filepath.Join(dir, "vppagent.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	go func() { _ = server.Serve(listener) }()
+
+	start := vppagentdial.StartFunc(&url.URL{Scheme: "unix", Path: socket}, time.Second, grpc.WithInsecure())
+	cc, errCh := start(ctx)
+	require.NotNil(t, cc)
+	require.Len(t, errCh, 0)
+
+	// Losing the connection counts as the vppagent exiting, without the forwarder having stopped it
+	server.Stop()
+	select {
+	case err = <-errCh:
+		require.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("lost connection was not reported")
+	}
+	_, ok := <-errCh
+	require.False(t, ok)
+}
+
+func TestStartFunc_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "vppagentdial")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	socket := filepath.Join(dir, "vppagent.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	go func() { _ = server.Serve(listener) }()
+	defer server.Stop()
+
+	start := vppagentdial.StartFunc(&url.URL{Scheme: "unix", Path: socket}, time.Second, grpc.WithInsecure())
+	cc, errCh := start(ctx)
+	require.NotNil(t, cc)
+
+	// Cancelling closes the connection, without counting as the vppagent exiting
+	cancel()
+	select {
+	case _, ok := <-errCh:
+		require.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("error channel was not closed")
+	}
+	require.Equal(t, connectivity.Shutdown, cc.(*grpc.ClientConn).GetState())
+}
+
+func TestStartFunc_Unreachable(t *testing.T) {
+	// The dial times out on its own, as the supervisor never cancels the context of a restart
+	start := vppagentdial.StartFunc(&url.URL{Scheme: "unix", Path: filepath.Join(os.TempDir(), "missing.sock")}, 100*time.Millisecond, grpc.WithInsecure())
+	cc, errCh := start(context.Background())
+	require.Nil(t, cc)
+	select {
+	case err := <-errCh:
+		require.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("dial did not time out")
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	config, err := vppagentdial.TLSConfig("", "", "")
+	require.NoError(t, err)
+	require.Nil(t, config.RootCAs)
+
+	_, err = vppagentdial.TLSConfig(filepath.Join(os.TempDir(), "missing.crt"), "", "")
+	require.Error(t, err)
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !windows
+
+package vppinit
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.ligato.io/vpp-agent/v3/proto/ligato/configurator"
+	"go.ligato.io/vpp-agent/v3/proto/ligato/vpp"
+	"google.golang.org/grpc"
+)
+
+// Apply - applies the initial vpp configuration created by initFunc to the vppagent reached by vppagentCC.
+// sdk-vppagent only applies it along with the first connection, so this is needed to restore it after the
+// vppagent has been restarted.
+func Apply(ctx context.Context, vppagentCC grpc.ClientConnInterface, initFunc func(conf *configurator.Config) error) error {
+	conf := &configurator.Config{
+		VppConfig: &vpp.ConfigData{},
+	}
+	if err := initFunc(conf); err != nil {
+		return err
+	}
+	_, err := configurator.NewConfiguratorServiceClient(vppagentCC).Update(ctx, &configurator.UpdateRequest{Update: conf}, grpc.WaitForReady(true))
+	return errors.Wrap(err, "failed to apply initial vpp configuration")
+}
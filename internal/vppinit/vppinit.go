@@ -28,10 +28,7 @@ import (
 
 // Func - returns the a function to create an initial vpp configuration
 func Func(srcIP net.IP) func(conf *configurator.Config) error {
-	var err error
-	if srcIP == nil || srcIP.IsUnspecified() {
-		srcIP, err = defaultTunnelIP()
-	}
+	srcIP, err := TunnelIP(srcIP)
 	return func(conf *configurator.Config) error {
 		if err != nil {
 			return errors.Wrap(err, "No tunnel IP provided")
@@ -51,3 +48,12 @@ func Func(srcIP net.IP) func(conf *configurator.Config) error {
 		return nil
 	}
 }
+
+// TunnelIP - returns srcIP or, if it is unset, the IP of the first interface with a usable address, which is the IP
+//            tunnels are originated and terminated on
+func TunnelIP(srcIP net.IP) (net.IP, error) {
+	if srcIP == nil || srcIP.IsUnspecified() {
+		return defaultTunnelIP()
+	}
+	return srcIP, nil
+}
@@ -20,24 +20,37 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/networkservicemesh/sdk/pkg/tools/jaeger"
 	"github.com/networkservicemesh/sdk/pkg/tools/spanhelper"
 
 	nested "github.com/antonfisher/nested-logrus-formatter"
 	"github.com/edwarnicke/grpcfd"
-	"github.com/golang/protobuf/ptypes"
 	"github.com/kelseyhightower/envconfig"
+	"github.com/pkg/errors"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
 	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
 	"github.com/spiffe/go-spiffe/v2/workloadapi"
+	"go.ligato.io/vpp-agent/v3/proto/ligato/configurator"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
 
+	"github.com/networkservicemesh/api/pkg/api"
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
 	registryapi "github.com/networkservicemesh/api/pkg/api/registry"
 	registrysendfd "github.com/networkservicemesh/sdk/pkg/registry/common/sendfd"
 	registrychain "github.com/networkservicemesh/sdk/pkg/registry/core/chain"
@@ -46,47 +59,185 @@ import (
 	"github.com/networkservicemesh/sdk-vppagent/pkg/tools/vppagent"
 
 	"github.com/networkservicemesh/sdk/pkg/networkservice/common/authorize"
+	"github.com/networkservicemesh/sdk/pkg/networkservice/core/chain"
 	"github.com/networkservicemesh/sdk/pkg/tools/spiffejwt"
 
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 
 	"github.com/networkservicemesh/sdk/pkg/tools/debug"
-	"github.com/networkservicemesh/sdk/pkg/tools/grpcutils"
 	"github.com/networkservicemesh/sdk/pkg/tools/log"
 	"github.com/networkservicemesh/sdk/pkg/tools/signalctx"
 
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/admin"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/admission"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/configloader"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/connstats"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/conntrack"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/drain"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/failover"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/fileidentity"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/gate"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/healthcheck"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/httpserve"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/inoderesolve"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/listen"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/logging"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/metrics"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/nselabels"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/nsename"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/peerauthz"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/quota"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/reconcile"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/registration"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/regopolicy"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/startup"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/supervisor"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/tracing"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/vppagentdial"
 	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/vppinit"
 )
 
+const (
+	// registryHealthService - name of the health service reflecting the status of our registration with the registry
+	registryHealthService = "registry.NetworkServiceEndpointRegistry"
+	// vppagentHealthService - name of the health service reflecting whether vppagent is running
+	vppagentHealthService = "vppagent"
+	// dataplaneHealthService - name of the health service reflecting whether the initial vpp configuration, with the
+	// uplink used for tunnels, is applied
+	dataplaneHealthService = "dataplane"
+	// configFileEnv - environment variable naming the config file, unless given by the -config flag
+	configFileEnv = "NSM_CONFIG_FILE"
+	// tmpDirPrefix - prefix of the name of the temporary directory holding our sockets
+	tmpDirPrefix = "forwarder-"
+	// adminRequestTimeout - timeout of the requests to the admin API of the running forwarder made by runCommand
+	adminRequestTimeout = 30 * time.Second
+	// nodeNameEnv - environment variable giving the name of the node, as set from spec.nodeName by the downward API
+	nodeNameEnv = "NODE_NAME"
+	// podNameEnv - environment variable giving the name of the pod, as set from metadata.name by the downward API
+	podNameEnv = "POD_NAME"
+	// vppVersionTimeout - timeout of querying the local vppagent for the version of VPP
+	vppVersionTimeout = 5 * time.Second
+)
+
+// version - version of the forwarder, set with -ldflags "-X main.version=..." at build time
+var version = "unknown"
+
+// Exit codes of the forwarder when a startup phase fails or times out.  Those of phases added later come last, so
+// that the exit codes of the others stay the same.
+const (
+	exitCodeConfig = iota + 10
+	exitCodeVppagent
+	exitCodeSvid
+	exitCodeReconcile
+	exitCodeEndpoint
+	exitCodeServer
+	exitCodeRegistration
+	exitCodeName
+)
+
 // Config - configuration for cmd-forwarder-vppagent
 type Config struct {
-	Name             string        `default:"forwarder" desc:"Name of Endpoint"`
+	Name             string        `desc:"Name of Endpoint, forwarder-<node name>, $POD_NAME or forwarder-<hostname> if empty"`
 	NSName           string        `default:"xconnectns" desc:"Name of Network Service to Register with Registry"`
 	TunnelIP         net.IP        `desc:"IP to use for tunnels" split_words:"true"`
-	ConnectTo        url.URL       `default:"unix:///connect.to.socket" desc:"url to connect to" split_words:"true"`
+	ConnectTo        []url.URL     `default:"unix:///connect.to.socket" desc:"urls of the NSMgr to connect to, for the registry and outgoing connections" split_words:"true"`
+	ConnectToPolicy  string        `default:"priority" desc:"how to choose between the ConnectTo urls: priority, failing over in order, or round-robin over the healthy ones" split_words:"true"`
+	ListenOn         []url.URL     `desc:"urls to listen on, unix:///path or tcp://host:port, the first of which is registered; a socket in a temporary directory if empty" split_words:"true"`
 	MaxTokenLifetime time.Duration `default:"24h" desc:"maximum lifetime of tokens" split_words:"true"`
+	// RegistrationExpiration - the registration is refreshed well before it expires, so this bounds how long a
+	// stale registration can outlive the forwarder
+	RegistrationExpiration time.Duration `default:"1m" desc:"expiration period of the registration with the registry" split_words:"true"`
+	ShutdownGracePeriod    time.Duration `default:"15s" desc:"maximum time to spend on an orderly shutdown" split_words:"true"`
+	LogLevel               string        `default:"INFO" desc:"Log level: panic, fatal, error, warn, info, debug or trace.  SIGUSR1/SIGUSR2 raise/lower it at runtime" split_words:"true"`
+	LogFormat              string        `default:"text" desc:"Log format: text or json" split_words:"true"`
+	VppagentMaxRestarts    int           `default:"5" desc:"number of consecutive failed vppagent restarts after which the forwarder exits" split_words:"true"`
+	// StateFile - when set, active connections are left in place on shutdown rather than Closed, for the next
+	// forwarder instance to restore from this file
+	StateFile     string        `desc:"file in which to persist active connections, for the next forwarder instance to restore; requires MemifSocketDir" split_words:"true"`
+	MetricsPort   int           `default:"9090" desc:"TCP port to serve Prometheus metrics on at /metrics, 0 to disable" split_words:"true"`
+	StatsInterval time.Duration `default:"10s" desc:"interval at which the VPP interface counters of connections are read, 0 to disable" split_words:"true"`
+	AdminPort     int           `default:"9091" desc:"TCP port on 127.0.0.1 to serve the admin API on, 0 to disable" split_words:"true"`
+	HealthPort    int           `default:"9092" desc:"TCP port to serve the /livez and /readyz HTTP probes on, 0 to disable" split_words:"true"`
+	// AllowedClientIDs, AllowedServerIDs - SPIFFE ID patterns, as accepted by peerauthz.NewPolicy(...)
+	AllowedClientIDs []string `desc:"SPIFFE IDs allowed to call the forwarder: IDs, ID/* path prefixes or spiffe://<trust domain>, any if empty" split_words:"true"`
+	AllowedServerIDs []string `desc:"SPIFFE IDs allowed for the NSMgr and registry the forwarder dials: IDs, ID/* path prefixes or spiffe://<trust domain>, any if empty" split_words:"true"`
+	PolicyFiles      []string `desc:"Rego policy files, each defining an allow rule that Requests must satisfy; reloaded when they change" split_words:"true"`
+	// IdentityProvider - "spire" gets the X509-SVID and trust bundle from the SPIRE agent's Workload API, "file" from
+	// TLSCertFile, TLSKeyFile and TLSCAFile
+	IdentityProvider string `default:"spire" desc:"source of the forwarder's X509-SVID and trust bundle: spire or file" split_words:"true"`
+	TLSCertFile      string `desc:"PEM certificate, with the forwarder's SPIFFE ID as URI SAN, for the file identity provider" split_words:"true"`
+	TLSKeyFile       string `desc:"PEM private key of TLSCertFile, for the file identity provider" split_words:"true"`
+	TLSCAFile        string `desc:"PEM CA certificates trusted to authenticate peers, for the file identity provider" split_words:"true"`
+	// VppagentTimeout, ..., RegistrationTimeout - timeouts of the startup phases, after which the forwarder exits with
+	// the exit code of the phase.  0 disables the timeout.
+	VppagentTimeout     time.Duration `default:"1m" desc:"timeout of startup phase 2, running vppagent" split_words:"true"`
+	SvidTimeout         time.Duration `default:"1m" desc:"timeout of startup phase 3, retrieving the SVID" split_words:"true"`
+	NameTimeout         time.Duration `default:"1m" desc:"timeout of startup phase 4, connecting to the registry and choosing a name" split_words:"true"`
+	ReconcileTimeout    time.Duration `default:"1m" desc:"timeout of startup phase 5, removing stale artifacts" split_words:"true"`
+	EndpointTimeout     time.Duration `default:"2m" desc:"timeout of startup phase 6, creating the endpoint and restoring connections" split_words:"true"`
+	ServerTimeout       time.Duration `default:"30s" desc:"timeout of startup phase 7, creating the grpc server" split_words:"true"`
+	RegistrationTimeout time.Duration `default:"1m" desc:"timeout of startup phase 8, registering with the registry" split_words:"true"`
+	StartupReportFile   string        `desc:"file to write a JSON report of a failed startup phase to, such as /dev/termination-log" split_words:"true"`
+	// VppagentURL - when set, the forwarder neither starts nor stops vppagent, and only (re)dials it
+	VppagentURL         url.URL `desc:"URL of an externally managed vppagent to dial, unix:///path or tcp://host:port, instead of starting one" split_words:"true"`
+	VppagentTLS         bool    `desc:"dial the external vppagent over TLS" split_words:"true"`
+	VppagentTLSCAFile   string  `desc:"PEM CA certificates of the external vppagent, the system's if empty" split_words:"true"`
+	VppagentTLSCertFile string  `desc:"PEM client certificate to authenticate with to the external vppagent, if any" split_words:"true"`
+	VppagentTLSKeyFile  string  `desc:"PEM private key of VppagentTLSCertFile" split_words:"true"`
+	// VppagentDialTimeout - a dial timing out counts as a failed vppagent restart
+	VppagentDialTimeout time.Duration `default:"15s" desc:"timeout of each dial of the external vppagent" split_words:"true"`
+	// MemifSocketDir - its memif sockets are kept on shutdown along with the connections when StateFile is set,
+	// and are otherwise removed with it
+	MemifSocketDir     string      `desc:"directory to create memif sockets in, a new temporary directory if empty" split_words:"true"`
+	MemifSocketDirMode os.FileMode `default:"0700" desc:"permissions of MemifSocketDir, such as 0750 to let the members of its group use memif" split_words:"true"`
+	MemifSocketDirUID  int         `default:"-1" desc:"owner of MemifSocketDir, -1 to leave it unchanged" split_words:"true"`
+	MemifSocketDirGID  int         `default:"-1" desc:"group of MemifSocketDir, -1 to leave it unchanged" split_words:"true"`
+	// NodeName, MaxConnections and Labels - advertised in the labels of the registration, along with the tunnel IP,
+	// the supported mechanisms and the versions of VPP and of the forwarder
+	NodeName       string            `desc:"name of the node the forwarder runs on, $NODE_NAME or the hostname if empty" split_words:"true"`
+	NamePolicy     string            `default:"fail" desc:"when Name is registered by a forwarder on another node: fail, or suffix it with -2, -3, ..." split_words:"true"`
+	MaxConnections int               `desc:"maximum number of active connections, advertised as capacity, beyond which Requests are rejected, 0 for no limit" split_words:"true"`
+	Labels         map[string]string `desc:"extra labels to register the forwarder with, key:value,... taking precedence over the forwarder's own"`
+	// MaxInflightRequests - along with MaxConnections, Requests in excess are rejected with ResourceExhausted, for the
+	// NSMgr to retry with another forwarder
+	MaxInflightRequests int `desc:"maximum number of Requests handled at once, beyond which Requests are rejected, 0 for no limit" split_words:"true"`
+	// ClientQuotas - the quota of each client is that of the most specific pattern matching its SPIFFE ID, and is
+	// enforced for each client separately
+	ClientQuotas []string `desc:"per-client quotas, <SPIFFE ID pattern>=<max connections>/<max Requests per second>,... 0 for no limit, patterns as for AllowedClientIDs" split_words:"true"`
+	// TracingExporter, ..., TracingOTLPAddress - the spans of the NetworkService calls are tagged with the id,
+	// network service and mechanism of their connection
+	TracingExporter      string  `default:"jaeger" desc:"where to export spans to: jaeger, the agent configured by the JAEGER_* environment variables, otlp or none" split_words:"true"`
+	TracingSamplingRatio float64 `default:"1" desc:"ratio of the traces started by the forwarder that are sampled, from 0 to 1" split_words:"true"`
+	TracingServiceName   string  `desc:"service name of the spans, cmd-forwarder-vppagent@<hostname> if empty" split_words:"true"`
+	TracingOTLPAddress   string  `default:"localhost:55680" desc:"host:port of the OpenTelemetry collector to export spans to with the otlp exporter" split_words:"true"`
 }
 
 func main() {
+	configFile := flag.String("config", os.Getenv(configFileEnv),
+		"YAML or JSON config file, whose values are overridden by NSM_* environment variables")
+	flag.Parse()
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(*configFile, flag.Args()...))
+	}
+
 	// ********************************************************************************
 	// setup context to catch signals
 	// ********************************************************************************
+	// ctx is done when the forwarder should begin shutting down.  Everything that must keep working while it does is
+	// run on runCtx instead, which is only cancelled once the shutdown has completed.
 	ctx := signalctx.WithSignals(context.Background())
 	ctx, cancel := context.WithCancel(ctx)
+	runCtx, runCancel := context.WithCancel(context.Background())
+	defer runCancel()
 
 	// ********************************************************************************
-	// setup logging
+	// setup logging, until the configured level and format are known from phase 1
 	// ********************************************************************************
 	logrus.SetFormatter(&nested.Formatter{})
-	logrus.SetLevel(logrus.TraceLevel)
+	logrus.SetLevel(logrus.InfoLevel)
 	ctx = log.WithField(ctx, "cmd", os.Args[0])
-
-	// ********************************************************************************
-	// Configure open tracing
-	// ********************************************************************************
-	jaegerCloser := jaeger.InitJaeger("cmd-forwarder-vppagent")
-	defer func() { _ = jaegerCloser.Close() }()
+	runCtx = log.WithField(runCtx, "cmd", os.Args[0])
 
 	// ********************************************************************************
 	// Debug self if necessary
@@ -95,162 +246,738 @@ func main() {
 		log.Entry(ctx).Infof("%s", err)
 	}
 
-	starttime := time.Now()
+	forwarderMetrics := metrics.New()
+	startupSequence := startup.New(ctx, startup.WithBeginFunc(forwarderMetrics.StartPhase))
 
 	// enumerating phases
-	log.Entry(ctx).Infof("there are 6 phases which will be executed followed by a success message:")
+	log.Entry(ctx).Infof("there are 8 phases which will be executed followed by a success message:")
 	log.Entry(ctx).Infof("the phases include:")
-	log.Entry(ctx).Infof("1: get config from environment")
+	log.Entry(ctx).Infof("1: get config from file and environment")
 	log.Entry(ctx).Infof("2: run vppagent and get a connection to it")
 	log.Entry(ctx).Infof("3: retrieve spiffe svid")
-	log.Entry(ctx).Infof("4: create xconnect network service endpoint")
-	log.Entry(ctx).Infof("5: create grpc server and register xconnect")
-	log.Entry(ctx).Infof("6: register xconnectns with the registry")
+	log.Entry(ctx).Infof("4: connect to the registry and choose a name no forwarder on another node is registered with")
+	log.Entry(ctx).Infof("5: remove stale artifacts of a previous forwarder instance")
+	log.Entry(ctx).Infof("6: create xconnect network service endpoint and restore connections")
+	log.Entry(ctx).Infof("7: create grpc server and register xconnect")
+	log.Entry(ctx).Infof("8: register xconnectns with the registry")
 	log.Entry(ctx).Infof("a final success message with start time duration")
 
 	// ********************************************************************************
-	log.Entry(ctx).Infof("executing phase 1: get config from environment (time since start: %s)", time.Since(starttime))
+	startupSequence.Begin(&startup.Phase{
+		Name:        "config",
+		Description: "get config from file and environment",
+		ExitCode:    exitCodeConfig,
+		WaitingOn:   *configFile,
+	})
 	// ********************************************************************************
-	config := &Config{}
-	if err := envconfig.Usage("nsm", config); err != nil {
-		logrus.Fatal(err)
-	}
-	if err := envconfig.Process("nsm", config); err != nil {
-		logrus.Fatalf("error processing config from env: %+v", err)
-	}
+	config := loadConfig(startupSequence, *configFile)
+	logging.HandleSignals(runCtx)
 
 	log.Entry(ctx).Infof("Config: %#v", config)
+	tracingCloser, err := initTracing(ctx, config)
+	startupSequence.Check(err)
+	defer func() { _ = tracingCloser.Close() }()
+	serveMetrics(ctx, runCtx, startupSequence, cancel, config.MetricsPort, forwarderMetrics)
+	healthServer := health.NewServer()
+	forwarderHealth := newHealth(ctx, healthServer)
+	serveHealth(ctx, runCtx, startupSequence, cancel, config.HealthPort, forwarderHealth)
+	incomingAuthorizer, err := peerAuthorizer(ctx, "incoming", config.AllowedClientIDs, forwarderMetrics)
+	startupSequence.Check(err)
+	outgoingAuthorizer, err := peerAuthorizer(ctx, "outgoing", config.AllowedServerIDs, forwarderMetrics)
+	startupSequence.Check(err)
+	policies, err := loadPolicies(ctx, runCtx, config.PolicyFiles)
+	startupSequence.Check(err)
+	quotas, err := quota.Parse(config.ClientQuotas...)
+	startupSequence.Check(errors.Wrap(err, "invalid client quotas"))
+	registryReconnectCh := make(chan struct{}, 1)
+	nsmgrOptions, registryOptions, err := connectToOptions(config, registryReconnectCh)
+	startupSequence.Check(err)
 
 	// ********************************************************************************
-	log.Entry(ctx).Infof("executing phase 2: run vppagent and get a connection to it (time since start: %s)", time.Since(starttime))
+	startupSequence.Begin(&startup.Phase{
+		Name:        "vppagent",
+		Description: "run vppagent and get a connection to it",
+		ExitCode:    exitCodeVppagent,
+		Timeout:     config.VppagentTimeout,
+		WaitingOn:   vppagentAddress(config),
+	})
 	// ********************************************************************************
-	// Run vppagent and get a connection to it
-	vppagentCC, vppagentErrCh := vppagent.StartAndDialContext(ctx)
-	exitOnErrCh(ctx, cancel, vppagentErrCh)
+	startFunc, err := vppagentStartFunc(config)
+	startupSequence.Check(err)
+	// Run vppagent and get a connection to it, which stays usable across restarts of vppagent
+	vppagentCC := supervisor.New(runCtx, startFunc,
+		supervisor.WithMaxRestarts(config.VppagentMaxRestarts),
+		supervisor.WithStatusFunc(func(err error) { forwarderHealth.Set(vppagentHealthService, err) }),
+	)
+	vppagentErrCh := vppagentCC.Start()
+	exitOnErrCh(ctx, startupSequence, cancel, vppagentErrCh)
 
 	// ********************************************************************************
-	log.Entry(ctx).Infof("executing phase 3: retrieving svid, check spire agent logs if this is the last line you see (time since start: %s)", time.Since(starttime))
+	startupSequence.Begin(&startup.Phase{
+		Name:        "svid",
+		Description: "retrieve spiffe svid",
+		ExitCode:    exitCodeSvid,
+		Timeout:     config.SvidTimeout,
+		WaitingOn:   identityWaitingOn(config),
+	})
 	// ********************************************************************************
-	source, err := workloadapi.NewX509Source(ctx)
-	if err != nil {
-		logrus.Fatalf("error getting x509 source: %+v", err)
-	}
+	source, err := x509Source(ctx, runCtx, config)
+	startupSequence.Check(err)
 	svid, err := source.GetX509SVID()
-	if err != nil {
-		logrus.Fatalf("error getting x509 svid: %+v", err)
-	}
+	startupSequence.Check(errors.Wrap(err, "error getting x509 svid"))
 	logrus.Infof("SVID: %q", svid.ID)
 
 	// ********************************************************************************
-	log.Entry(ctx).Infof("executing phase 4: create xconnect network service endpoint (time since start: %s)", time.Since(starttime))
+	startupSequence.Begin(&startup.Phase{
+		Name:        "name",
+		Description: "connect to the registry and choose a name no forwarder on another node is registered with",
+		ExitCode:    exitCodeName,
+		Timeout:     config.NameTimeout,
+		WaitingOn:   failover.String(config.ConnectTo),
+	})
+	// ********************************************************************************
+	registryCreds := credentials.NewTLS(tlsconfig.MTLSClientConfig(source, source, outgoingAuthorizer))
+	registryOptions = append(
+		append(registryOptions, spanhelper.WithTracingDial()...),
+		grpc.WithTransportCredentials(grpcfd.TransportCredentials(registryCreds)),
+		grpc.WithBlock(),
+	)
+	registryCC, err := grpc.DialContext(ctx,
+		failover.URL("registry").String(),
+		registryOptions...,
+	)
+	startupSequence.Check(errors.Wrap(err, "failed to connect to registry"))
+	registryClient := registrychain.NewNetworkServiceEndpointRegistryClient(
+		registrysendfd.NewNetworkServiceEndpointRegistryClient(),
+		registryapi.NewNetworkServiceEndpointRegistryClient(registryCC),
+	)
+	config.Name, err = uniqueName(ctx, registryClient, config)
+	startupSequence.Check(err)
+
+	// ********************************************************************************
+	startupSequence.Begin(&startup.Phase{
+		Name:        "reconcile",
+		Description: "remove stale artifacts of a previous forwarder instance",
+		ExitCode:    exitCodeReconcile,
+		Timeout:     config.ReconcileTimeout,
+		WaitingOn:   vppagentAddress(config),
+	})
 	// ********************************************************************************
+	tracker := conntrack.NewServer(conntrack.WithStateFile(config.StateFile))
+	removeStaleArtifacts(ctx, vppagentCC, config.MemifSocketDir, tracker)
 
-	tmpDir, err := ioutil.TempDir("", "forwarder-")
-	if err != nil {
-		logrus.Fatalf("error creating tmpDir %+v", err)
-	}
+	// ********************************************************************************
+	startupSequence.Begin(&startup.Phase{
+		Name:        "endpoint",
+		Description: "create xconnect network service endpoint and restore connections",
+		ExitCode:    exitCodeEndpoint,
+		Timeout:     config.EndpointTimeout,
+		WaitingOn:   vppagentAddress(config) + ", to apply the initial vpp configuration and restored connections",
+	})
+	// ********************************************************************************
+	tmpDir, err := ioutil.TempDir("", tmpDirPrefix)
+	startupSequence.Check(errors.Wrap(err, "error creating tmpDir"))
 	defer func(tmpDir string) { _ = os.Remove(tmpDir) }(tmpDir)
-	memifSocketDir := filepath.Join(tmpDir, "memif")
-	err = os.Mkdir(memifSocketDir, 0700)
-	if err != nil {
-		logrus.Fatalf("error creating dir %s: %+v", memifSocketDir, err)
-	}
+	memifSocketDir, createdMemifSocketDir, err := createMemifSocketDir(config, tmpDir)
+	startupSequence.Check(err)
 	clientOptions := append(
 		spanhelper.WithTracingDial(),
-		grpc.WithTransportCredentials(grpcfd.TransportCredentials(credentials.NewTLS(tlsconfig.MTLSClientConfig(source, source, tlsconfig.AuthorizeAny())))),
+		grpc.WithTransportCredentials(grpcfd.TransportCredentials(credentials.NewTLS(tlsconfig.MTLSClientConfig(source, source, outgoingAuthorizer)))),
 		grpc.WithDefaultCallOptions(grpc.WaitForReady(true)),
+		grpc.WithChainUnaryInterceptor(tracker.UnaryClientInterceptor()),
+	)
+	clientOptions = append(clientOptions, nsmgrOptions...)
+	gateServer := gate.NewServer(gate.WithActiveFunc(tracker.Tracks))
+	quotaServer := quota.NewServer(quotas,
+		quota.WithConnections(tracker.Connections, tracker.Tracks),
+		quota.WithRejectFunc(forwarderMetrics.RequestRejected),
 	)
+	forwarderMetrics.SetActiveConnectionsFunc(tracker.Len)
+	collector := connstats.New(configurator.NewStatsPollerServiceClient(vppagentCC), tracker, connstats.WithInterval(config.StatsInterval))
+	forwarderMetrics.Register(collector)
+	collector.Start(runCtx)
+	vppinitFunc := vppinit.Func(config.TunnelIP)
 	endpoint := xconnectns.NewServer(
-		ctx,
+		runCtx,
 		config.Name,
-		authorize.NewServer(),
+		chain.NewNetworkServiceServer(
+			forwarderMetrics.NewServer(tracker.Outgoing),
+			gateServer,
+			admission.NewServer(
+				admission.WithMaxInflightRequests(config.MaxInflightRequests),
+				admission.WithMaxConnections(config.MaxConnections, tracker.Len, tracker.Tracks),
+				admission.WithRejectFunc(forwarderMetrics.RequestRejected),
+			),
+			tracker,
+			inoderesolve.NewServer(),
+			authorize.NewServer(),
+			quotaServer,
+			policies.NewServer(),
+		),
 		spiffejwt.TokenGeneratorFunc(source, config.MaxTokenLifetime),
-		vppagentCC,
+		collector.WrapVppagent(forwarderMetrics.WrapVppagent(vppagentCC)),
 		memifSocketDir,
 		config.TunnelIP,
-		vppinit.Func(config.TunnelIP),
-		&config.ConnectTo,
+		vppinitFunc,
+		failover.URL("nsmgr"),
 		clientOptions...,
 	)
+	addRestartHooks(vppagentCC, vppinitFunc, tracker, endpoint, forwarderHealth)
+	forwarderHealth.Set(dataplaneHealthService, vppinit.Apply(ctx, vppagentCC, vppinitFunc))
+	startupSequence.Check(restoreConnections(ctx, config.StateFile, tracker, endpoint))
 
 	// ********************************************************************************
-	log.Entry(ctx).Infof("executing phase 5: create grpc server and register xconnect (time since start: %s)", time.Since(starttime))
+	listenOn := listenURLs(config, tmpDir)
+	startupSequence.Begin(&startup.Phase{
+		Name:        "server",
+		Description: "create grpc server and register xconnect",
+		ExitCode:    exitCodeServer,
+		Timeout:     config.ServerTimeout,
+		WaitingOn:   urlsString(listenOn),
+	})
 	// ********************************************************************************
 	options := append(
 		spanhelper.WithTracing(),
+		grpc.ChainUnaryInterceptor(tracing.ServerInterceptor()),
 		grpc.Creds(
 			grpcfd.TransportCredentials(
 				credentials.NewTLS(
 					tlsconfig.MTLSServerConfig(
 						source,
 						source,
-						tlsconfig.AuthorizeAny()),
+						incomingAuthorizer),
 				),
 			),
 		),
 	)
 	server := grpc.NewServer(options...)
-	endpoint.Register(server)
-	listenOn := &(url.URL{Scheme: "unix", Path: filepath.Join(tmpDir, "listen.on")})
-	srvErrCh := grpcutils.ListenAndServe(ctx, listenOn, server)
-	exitOnErrCh(ctx, cancel, srvErrCh)
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+	// The overall health of the server is reported as the "" service
+	forwarderHealth.AddServices(append([]string{""}, api.ServiceNames(endpoint)...)...)
+	networkservice.RegisterNetworkServiceServer(server, endpoint)
+	networkservice.RegisterMonitorConnectionServer(server, collector.MonitorServer(endpoint))
+	srvErrCh := listen.Serve(runCtx, listenOn, server)
+	exitOnErrCh(ctx, startupSequence, cancel, srvErrCh)
 
 	// ********************************************************************************
-	log.Entry(ctx).Infof("executing phase 6: register %s with the registry (time since start: %s)", config.NSName, time.Since(starttime))
+	startupSequence.Begin(&startup.Phase{
+		Name:        "registration",
+		Description: fmt.Sprintf("register %s with the registry", config.NSName),
+		ExitCode:    exitCodeRegistration,
+		Timeout:     config.RegistrationTimeout,
+		WaitingOn:   failover.String(config.ConnectTo),
+	})
 	// ********************************************************************************
-	registryCreds := credentials.NewTLS(tlsconfig.MTLSClientConfig(source, source, tlsconfig.AuthorizeAny()))
-	registryOptions := append(
-		spanhelper.WithTracingDial(),
-		grpc.WithTransportCredentials(grpcfd.TransportCredentials(registryCreds)),
-		grpc.WithBlock(),
+	registrar := registration.NewRegistrar(runCtx,
+		registryClient,
+		&registryapi.NetworkServiceEndpoint{
+			Name:                config.Name,
+			NetworkServiceNames: []string{config.NSName},
+			NetworkServiceLabels: map[string]*registryapi.NetworkServiceLabels{
+				config.NSName: {Labels: registrationLabels(ctx, config)},
+			},
+			Url: registrationURL(ctx, config, listenOn[0]),
+		},
+		registration.WithExpirationPeriod(config.RegistrationExpiration),
+		registration.WithClientConn(registryCC),
+		registration.WithReconnectCh(registryReconnectCh),
+		registration.WithStatusFunc(registrationStatusFunc(ctx, forwarderHealth, forwarderMetrics)),
 	)
-	registryCC, err := grpc.DialContext(ctx,
-		config.ConnectTo.String(),
-		registryOptions...,
+	startupSequence.Check(registrar.Register())
+	serveAdmin(ctx, runCtx, startupSequence, cancel, config.AdminPort, tracker, quotaServer, drain.New(gateServer, registrar, tracker.Len))
+
+	startupSequence.End()
+
+	<-ctx.Done()
+	(&shutdownSequence{
+		gracePeriod:     config.ShutdownGracePeriod,
+		registrar:       registrar,
+		health:          forwarderHealth,
+		gateServer:      gateServer,
+		tracker:         tracker,
+		keepConnections: config.StateFile != "",
+		memifSocketDir:  memifSocketDir,
+		createdDir:      createdMemifSocketDir,
+		endpoint:        endpoint,
+		server:          server,
+	}).run(runCtx)
+	runCancel()
+	<-srvErrCh
+	<-vppagentErrCh
+}
+
+// runCommand - runs the command given on the command line instead of the forwarder and returns the exit code.
+// The commands are:
+//     config print - prints the effective config, with the source of each of its values
+//     drain        - drains the running forwarder through its admin API, and prints the resulting drain status
+//     drain status - prints the drain status of the running forwarder
+func runCommand(configFile string, args ...string) int {
+	command := strings.Join(args, " ")
+	if command != "config print" && command != "drain" && command != "drain status" {
+		_, _ = fmt.Fprintf(os.Stderr, "usage: %s [-config <file>] [config print | drain [status]]\n", os.Args[0])
+		return 2
+	}
+	config := &Config{}
+	fields, err := configloader.Load("nsm", config, configFile)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%+v\n", err)
+		return 1
+	}
+	switch command {
+	case "config print":
+		err = configloader.Print(os.Stdout, fields)
+	case "drain":
+		err = adminRequest(http.MethodPost, config.AdminPort, drain.Path)
+	default:
+		err = adminRequest(http.MethodGet, config.AdminPort, drain.Path)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "%+v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// adminRequest - sends a method request for path to the admin API of the running forwarder, served on port, and
+// prints the response
+func adminRequest(method string, port int, path string) error {
+	if port == 0 {
+		return errors.New("the admin API is disabled")
+	}
+	request, err := http.NewRequest(method, "http://"+adminAddress(port)+path, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	response, err := (&http.Client{Timeout: adminRequestTimeout}).Do(request)
+	if err != nil {
+		return errors.Wrap(err, "failed to reach the admin API of the forwarder")
+	}
+	defer func() { _ = response.Body.Close() }()
+	if _, err = io.Copy(os.Stdout, response.Body); err != nil {
+		return errors.WithStack(err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return errors.Errorf("%s %s: %s", method, path, response.Status)
+	}
+	return nil
+}
+
+// listenURLs - returns the urls to listen on: config.ListenOn, or a socket in tmpDir if it is empty
+func listenURLs(config *Config, tmpDir string) []*url.URL {
+	if len(config.ListenOn) == 0 {
+		return []*url.URL{{Scheme: "unix", Path: filepath.Join(tmpDir, "listen.on")}}
+	}
+	rv := make([]*url.URL, len(config.ListenOn))
+	for i := range config.ListenOn {
+		rv[i] = &config.ListenOn[i]
+	}
+	return rv
+}
+
+// urlsString - returns urls as a comma separated list, for display
+func urlsString(urls []*url.URL) string {
+	rv := make([]string, len(urls))
+	for i, u := range urls {
+		rv[i] = u.String()
+	}
+	return strings.Join(rv, ",")
+}
+
+// registrationURL - returns the url to register the forwarder listening on listenOn with.  When it listens on all the
+// IPs of a TCP port, that is the tunnel IP.
+func registrationURL(ctx context.Context, config *Config, listenOn *url.URL) string {
+	tunnelIP, err := vppinit.TunnelIP(config.TunnelIP)
+	if err != nil && listenOn.Scheme == "tcp" {
+		log.Entry(ctx).Warnf("registering %s as is: %+v", listenOn, err)
+	}
+	return listen.AdvertisedURL(listenOn, tunnelIP).String()
+}
+
+// connectToOptions - returns the options to dial the NSMgr at config.ConnectTo with, for outgoing connections and for
+// the registry.  reconnectCh receives whenever the registry is connected to through another of the urls.
+func connectToOptions(config *Config, reconnectCh chan<- struct{}) (nsmgrOptions, registryOptions []grpc.DialOption, err error) {
+	nsmgrOptions, err = failover.DialOptions(config.ConnectTo, config.ConnectToPolicy)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid ConnectTo")
+	}
+	registryOptions, err = failover.DialOptions(config.ConnectTo, config.ConnectToPolicy,
+		failover.WithReconnectFunc(func(address string) {
+			select {
+			case reconnectCh <- struct{}{}:
+			default:
+			}
+		}),
 	)
+	return nsmgrOptions, registryOptions, err
+}
+
+// registrationLabels - returns the labels to register the forwarder with.  Those that cannot be determined are left
+// out, with a warning.
+func registrationLabels(ctx context.Context, config *Config) map[string]string {
+	labels := &nselabels.Labels{
+		NodeName:         nodeName(config),
+		LocalMechanisms:  nselabels.LocalMechanisms,
+		RemoteMechanisms: nselabels.RemoteMechanisms,
+		ForwarderVersion: version,
+		Capacity:         config.MaxConnections,
+		Extra:            config.Labels,
+	}
+	tunnelIP, err := vppinit.TunnelIP(config.TunnelIP)
 	if err != nil {
-		log.Entry(ctx).Fatalf("failed to connect to registry: %+v", err)
+		log.Entry(ctx).Warnf("not advertising the tunnel IP: %+v", err)
 	}
+	labels.TunnelIP = tunnelIP
+	// The version of VPP is only known from the REST API of the vppagent we run ourselves
+	if config.VppagentURL.String() == "" {
+		versionCtx, cancelVersion := context.WithTimeout(ctx, vppVersionTimeout)
+		defer cancelVersion()
+		labels.VppVersion, err = nselabels.VppVersion(versionCtx, http.DefaultClient,
+			fmt.Sprintf("http://localhost:%d", vppagent.DefaultHTTPPort))
+		if err != nil {
+			log.Entry(ctx).Warnf("not advertising the version of VPP: %+v", err)
+		}
+	}
+	log.Entry(ctx).Infof("registering with labels %s", labels)
+	return labels.Map()
+}
 
-	registryClient := registrychain.NewNetworkServiceEndpointRegistryClient(
-		// TODO - add refresh
-		registrysendfd.NewNetworkServiceEndpointRegistryClient(),
-		registryapi.NewNetworkServiceEndpointRegistryClient(registryCC),
+// nodeName - returns the name of the node the forwarder runs on: config.NodeName, $NODE_NAME or the hostname
+func nodeName(config *Config) string {
+	if name := configuredNodeName(config); name != "" {
+		return name
+	}
+	name, _ := os.Hostname()
+	return name
+}
+
+// initTracing - sets up the tracer to export spans as configured by config
+func initTracing(ctx context.Context, config *Config) (io.Closer, error) {
+	serviceName := config.TracingServiceName
+	if serviceName == "" {
+		hostname, _ := os.Hostname()
+		serviceName = fmt.Sprintf("cmd-forwarder-vppagent@%s", hostname)
+	}
+	closer, err := tracing.Init(ctx, config.TracingExporter,
+		tracing.WithServiceName(serviceName),
+		tracing.WithSamplingRatio(config.TracingSamplingRatio),
+		tracing.WithOTLPAddress(config.TracingOTLPAddress),
 	)
-	// TODO - something smarter for expireTime
-	expireTime, err := ptypes.TimestampProto(time.Now().Add(config.MaxTokenLifetime))
 	if err != nil {
-		log.Entry(ctx).Fatalf("failed to connect to registry: %+v", err)
+		return nil, err
+	}
+	log.Entry(ctx).Infof("tracing with %s as %s, sampling %g of traces", config.TracingExporter, serviceName, config.TracingSamplingRatio)
+	return closer, nil
+}
+
+// configuredNodeName - returns the name of the node given by config.NodeName or $NODE_NAME, if any
+func configuredNodeName(config *Config) string {
+	if config.NodeName != "" {
+		return config.NodeName
+	}
+	return os.Getenv(nodeNameEnv)
+}
+
+// uniqueName - returns the name to register the forwarder with: config.Name, or a default derived from the names of
+// its node or pod, unless a forwarder on another node is registered with it through client, in which case it fails
+// or is suffixed according to config.NamePolicy
+func uniqueName(ctx context.Context, client registryapi.NetworkServiceEndpointRegistryClient, config *Config) (string, error) {
+	name := config.Name
+	if name == "" {
+		hostname, _ := os.Hostname()
+		name = nsename.Default(configuredNodeName(config), os.Getenv(podNameEnv), hostname)
+	}
+	unique, err := nsename.Unique(ctx, client, name, nodeName(config), config.NamePolicy)
+	if err != nil {
+		return "", err
+	}
+	if unique != name {
+		log.Entry(ctx).Warnf("name %s is taken, using %s instead", name, unique)
+	}
+	log.Entry(ctx).Infof("registering as %s", unique)
+	return unique, nil
+}
+
+// registrationStatusFunc - returns a function that reflects the outcome of each registration attempt in the
+// registryHealthService condition of forwarderHealth and in forwarderMetrics, logging whenever that status changes
+func registrationStatusFunc(ctx context.Context, forwarderHealth *healthcheck.Health, forwarderMetrics *metrics.Metrics) func(err error) {
+	var registered bool
+	return func(err error) {
+		forwarderMetrics.SetRegistered(err == nil)
+		forwarderHealth.Set(registryHealthService, err)
+		switch {
+		case err == nil && !registered:
+			log.Entry(ctx).Infof("registered with the registry")
+		case err != nil && registered:
+			log.Entry(ctx).Warnf("failed to refresh registration with the registry: %+v", err)
+		}
+		registered = err == nil
+	}
+}
+
+// startVppagent - starts vppagent and dials it, for use with supervisor.New(...)
+func startVppagent(ctx context.Context) (grpc.ClientConnInterface, <-chan error) {
+	return vppagent.StartAndDialContext(ctx)
+}
+
+// vppagentStartFunc - returns the supervisor.StartFunc dialing the external vppagent at config.VppagentURL, or
+// starting a local one if it is not set
+func vppagentStartFunc(config *Config) (supervisor.StartFunc, error) {
+	if config.VppagentURL.Scheme == "" {
+		return startVppagent, nil
+	}
+	if !config.VppagentTLS {
+		return vppagentdial.StartFunc(&config.VppagentURL, config.VppagentDialTimeout, grpc.WithInsecure()), nil
+	}
+	tlsConfig, err := vppagentdial.TLSConfig(config.VppagentTLSCAFile, config.VppagentTLSCertFile, config.VppagentTLSKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return vppagentdial.StartFunc(&config.VppagentURL, config.VppagentDialTimeout, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))), nil
+}
+
+// vppagentAddress - returns where the vppagent is reached with config
+func vppagentAddress(config *Config) string {
+	if config.VppagentURL.Scheme == "" {
+		return fmt.Sprintf("local vppagent on localhost:%d", vppagent.DefaultGrpcPort)
 	}
-	_, err = registryClient.Register(ctx, &registryapi.NetworkServiceEndpoint{
-		Name:                config.Name,
-		NetworkServiceNames: []string{config.NSName},
-		Url:                 listenOn.String(),
-		ExpirationTime:      expireTime,
+	return "external vppagent at " + config.VppagentURL.String()
+}
+
+// addRestartHooks - makes s restore the configuration of each restarted vppagent: first the initial vpp
+// configuration created by vppinitFunc, reflecting its outcome in the dataplaneHealthService condition of
+// forwarderHealth, then that of every connection tracked by tracker, by Requesting it again through endpoint
+func addRestartHooks(s *supervisor.Supervisor, vppinitFunc func(conf *configurator.Config) error, tracker *conntrack.Server,
+	endpoint networkservice.NetworkServiceServer, forwarderHealth *healthcheck.Health) {
+	s.AddRestartHook(func(ctx context.Context, cc grpc.ClientConnInterface) error {
+		err := vppinit.Apply(ctx, cc, vppinitFunc)
+		forwarderHealth.Set(dataplaneHealthService, err)
+		return err
+	})
+	s.AddRestartHook(func(ctx context.Context, _ grpc.ClientConnInterface) error {
+		// A connection that fails to be replayed is left for its client to heal, rather than failing the restart
+		if err := tracker.Replay(ctx, endpoint); err != nil {
+			log.Entry(ctx).Warnf("%+v", err)
+		}
+		return nil
 	})
+}
+
+// loadConfig - loads the config from configFile and the environment, and configures logging accordingly.  Failing to
+// do so fails the current phase of startupSequence.
+func loadConfig(startupSequence *startup.Sequence, configFile string) *Config {
+	config := &Config{}
+	if err := envconfig.Usage("nsm", config); err != nil {
+		startupSequence.Fail(errors.WithStack(err))
+	}
+	if _, err := configloader.Load("nsm", config, configFile); err != nil {
+		startupSequence.Fail(errors.Wrap(err, "error loading config"))
+	}
+	startupSequence.SetReportFile(config.StartupReportFile)
+	// A new temporary directory on each start would change the socket paths of restored memif connections
+	if config.StateFile != "" && config.MemifSocketDir == "" {
+		startupSequence.Fail(errors.New("MemifSocketDir must be set along with StateFile, for restored memif connections to keep their sockets"))
+	}
+	if config.TracingSamplingRatio < 0 || config.TracingSamplingRatio > 1 {
+		startupSequence.Fail(errors.Errorf("TracingSamplingRatio must be from 0 to 1, not %g", config.TracingSamplingRatio))
+	}
+	if err := logging.Configure(config.LogLevel, config.LogFormat); err != nil {
+		startupSequence.Fail(errors.Wrap(err, "error configuring logging"))
+	}
+	return config
+}
+
+// serveMetrics - serves the metrics over HTTP on port until runCtx is done, unless port is 0.  Failing to do so
+// cancels ctx.
+func serveMetrics(ctx, runCtx context.Context, startupSequence *startup.Sequence, cancel context.CancelFunc, port int, forwarderMetrics *metrics.Metrics) {
+	if port == 0 {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle(metrics.Path, forwarderMetrics.Handler())
+	exitOnErrCh(ctx, startupSequence, cancel, httpserve.ListenAndServe(runCtx, fmt.Sprintf(":%d", port), mux))
+}
+
+// newHealth - returns the Health of the forwarder, reported through healthServer
+func newHealth(ctx context.Context, healthServer *health.Server) *healthcheck.Health {
+	forwarderHealth := healthcheck.New(ctx, healthServer)
+	forwarderHealth.AddCondition(vppagentHealthService)
+	forwarderHealth.AddCondition(dataplaneHealthService)
+	forwarderHealth.AddCondition(registryHealthService)
+	return forwarderHealth
+}
+
+// serveHealth - serves the liveness and readiness probes of forwarderHealth over HTTP on port until runCtx is done,
+// unless port is 0.  Failing to do so cancels ctx.
+func serveHealth(ctx, runCtx context.Context, startupSequence *startup.Sequence, cancel context.CancelFunc, port int, forwarderHealth *healthcheck.Health) {
+	if port == 0 {
+		return
+	}
+	exitOnErrCh(ctx, startupSequence, cancel, httpserve.ListenAndServe(runCtx, fmt.Sprintf(":%d", port), forwarderHealth.Handler()))
+}
+
+// peerAuthorizer - returns an authorizer of the SPIFFE IDs of the incoming or outgoing (direction) mTLS peers
+// matching patterns, which logs and counts the peers it rejects
+func peerAuthorizer(ctx context.Context, direction string, patterns []string, forwarderMetrics *metrics.Metrics) (tlsconfig.Authorizer, error) {
+	policy, err := peerauthz.NewPolicy(patterns...)
 	if err != nil {
-		log.Entry(ctx).Fatalf("failed to connect to registry: %+v", err)
+		return nil, errors.Wrapf(err, "invalid SPIFFE IDs allowed for %s peers", direction)
 	}
+	return policy.Authorizer(func(id spiffeid.ID) {
+		log.Entry(ctx).Warnf("rejected %s mTLS peer %s", direction, id)
+		forwarderMetrics.PeerRejected(direction)
+	}), nil
+}
 
-	log.Entry(ctx).Infof("Startup completed in %v", time.Since(starttime))
+// serveAdmin - serves the admin API listing the connections tracked by tracker and the usage of the client quotas
+// enforced by quotaServer, and draining the forwarder with drainController, over HTTP on port of the loopback
+// interface until runCtx is done, unless port is 0.  Failing to do so cancels ctx.
+func serveAdmin(ctx, runCtx context.Context, startupSequence *startup.Sequence, cancel context.CancelFunc, port int, tracker *conntrack.Server,
+	quotaServer *quota.Server, drainController *drain.Controller) {
+	if port == 0 {
+		return
+	}
+	mux := http.NewServeMux()
+	adminHandler := admin.NewHandler(tracker)
+	mux.Handle(admin.Path, adminHandler)
+	mux.Handle(admin.Path+"/", adminHandler)
+	mux.Handle(quota.Path, quotaServer.Handler())
+	mux.Handle(drain.Path, drainController.Handler())
+	exitOnErrCh(ctx, startupSequence, cancel, httpserve.ListenAndServe(runCtx, adminAddress(port), mux))
+}
 
-	// TODO - cleaner shutdown across these three channels
-	<-ctx.Done()
-	<-srvErrCh
-	<-vppagentErrCh
+// adminAddress - returns the address the admin API is served on for port
+func adminAddress(port int) string {
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+}
+
+// identitySource - the X509-SVID and trust bundle of the forwarder
+type identitySource interface {
+	x509svid.Source
+	x509bundle.Source
+}
+
+// x509Source - returns the identitySource selected by config.IdentityProvider
+func x509Source(ctx, runCtx context.Context, config *Config) (identitySource, error) {
+	switch config.IdentityProvider {
+	case "spire":
+		source, err := workloadapi.NewX509Source(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "error getting x509 source")
+		}
+		return source, nil
+	case "file":
+		source, err := fileidentity.New(config.TLSCertFile, config.TLSKeyFile, config.TLSCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "error getting x509 source")
+		}
+		if err = source.Watch(runCtx); err != nil {
+			return nil, err
+		}
+		return source, nil
+	default:
+		return nil, errors.Errorf("unknown identity provider %q, expected spire or file", config.IdentityProvider)
+	}
+}
+
+// identityWaitingOn - returns what retrieving the SVID waits on with config.IdentityProvider
+func identityWaitingOn(config *Config) string {
+	if config.IdentityProvider == "file" {
+		return fmt.Sprintf("files %s, %s and %s", config.TLSCertFile, config.TLSKeyFile, config.TLSCAFile)
+	}
+	if address, ok := workloadapi.GetDefaultAddress(); ok {
+		return "SPIRE agent at " + address
+	}
+	return "SPIRE agent, with " + workloadapi.SocketEnv + " unset"
+}
+
+// loadPolicies - loads the Rego policies in files, and keeps them up to date until runCtx is done
+func loadPolicies(ctx, runCtx context.Context, files []string) (*regopolicy.Policies, error) {
+	policies, err := regopolicy.Load(ctx, files...)
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading policies")
+	}
+	if len(files) == 0 {
+		return policies, nil
+	}
+	if err = policies.Watch(runCtx); err != nil {
+		return nil, err
+	}
+	log.Entry(ctx).Infof("loaded policies %v", files)
+	return policies, nil
+}
+
+// removeStaleArtifacts - removes the dataplane artifacts left behind by a previous forwarder instance, except for
+// those of the connections tracker is going to restore
+func removeStaleArtifacts(ctx context.Context, vppagentCC grpc.ClientConnInterface, memifSocketDir string, tracker *conntrack.Server) {
+	persisted, err := tracker.Persisted()
+	if err != nil {
+		// Without knowing which connections will be restored, all of their artifacts would be removed
+		log.Entry(ctx).Errorf("not removing stale artifacts: %+v", err)
+		return
+	}
+	report, err := reconcile.Run(ctx, vppagentCC, filepath.Join(os.TempDir(), tmpDirPrefix+"*"), memifSocketDir, persisted)
+	if err != nil {
+		log.Entry(ctx).Errorf("%+v", err)
+	}
+	log.Entry(ctx).Infof("removed stale artifacts: %s", report)
+}
+
+// createMemifSocketDir - creates the directory to create memif sockets in, config.MemifSocketDir or a directory in
+// tmpDir, with the configured permissions and ownership, and returns whether it did not exist yet
+func createMemifSocketDir(config *Config, tmpDir string) (dir string, created bool, err error) {
+	dir = config.MemifSocketDir
+	if dir == "" {
+		dir = filepath.Join(tmpDir, "memif")
+	}
+	if _, err = os.Stat(dir); os.IsNotExist(err) {
+		created = true
+	}
+	if err = os.MkdirAll(dir, config.MemifSocketDirMode); err != nil {
+		return "", false, errors.Wrapf(err, "error creating dir %s", dir)
+	}
+	// The mode given to MkdirAll is subject to the umask, and dir may exist already
+	if err := os.Chmod(dir, config.MemifSocketDirMode); err != nil {
+		return "", false, errors.Wrapf(err, "error setting the mode of dir %s", dir)
+	}
+	if config.MemifSocketDirUID != -1 || config.MemifSocketDirGID != -1 {
+		if err := os.Chown(dir, config.MemifSocketDirUID, config.MemifSocketDirGID); err != nil {
+			return "", false, errors.Wrapf(err, "error setting the owner of dir %s", dir)
+		}
+	}
+	return dir, created, nil
+}
+
+// restoreConnections - restores the connections persisted in stateFile by the previous forwarder instance, if any
+func restoreConnections(ctx context.Context, stateFile string, tracker *conntrack.Server, endpoint networkservice.NetworkServiceServer) error {
+	if stateFile == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0700); err != nil {
+		return errors.Wrapf(err, "error creating dir for state file %s", stateFile)
+	}
+	restored, err := tracker.Restore(ctx, endpoint)
+	if err != nil {
+		log.Entry(ctx).Errorf("error restoring connections from %s: %+v", stateFile, err)
+	}
+	log.Entry(ctx).Infof("restored %d connections from %s", restored, stateFile)
+	return nil
 }
 
-func exitOnErrCh(ctx context.Context, cancel context.CancelFunc, errCh <-chan error) {
-	// If we already have an error, log it and exit
+func exitOnErrCh(ctx context.Context, startupSequence *startup.Sequence, cancel context.CancelFunc, errCh <-chan error) {
+	// If we already have an error, fail the current startup phase with it
 	select {
 	case err := <-errCh:
-		log.Entry(ctx).Fatal(err)
+		startupSequence.Check(err)
 	default:
 	}
-	// Otherwise wait for an error in the background to log and cancel
+	// Otherwise wait for an error in the background to log and cancel, after recording it for the phase then running
+	// to fail with it rather than with the cancellation
+	failed := startupSequence.Background()
 	go func(ctx context.Context, errCh <-chan error) {
-		err := <-errCh
-		log.Entry(ctx).Error(err)
+		if err, ok := <-errCh; ok {
+			log.Entry(ctx).Error(err)
+			failed(err)
+		}
 		cancel()
 	}(ctx, errCh)
 }
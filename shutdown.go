@@ -0,0 +1,149 @@
+// Copyright (c) 2020 Cisco and/or its affiliates.
+//
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build !windows
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+
+	"github.com/networkservicemesh/sdk/pkg/tools/log"
+
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/conntrack"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/gate"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/healthcheck"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/ifnames"
+	"github.com/networkservicemesh/cmd-forwarder-vppagent/internal/registration"
+)
+
+// shutdownSequence - the pieces of the forwarder that need to be wound down, in order, when it exits
+type shutdownSequence struct {
+	gracePeriod     time.Duration
+	registrar       *registration.Registrar
+	health          *healthcheck.Health
+	gateServer      *gate.Server
+	tracker         *conntrack.Server
+	keepConnections bool
+	memifSocketDir  string
+	createdDir      bool
+	endpoint        networkservice.NetworkServiceServer
+	server          *grpc.Server
+}
+
+// run - shuts the forwarder down within s.gracePeriod:
+//       1. unregisters from the registry, so that no new connections are routed to us
+//       2. reports NOT_SERVING for every health service
+//       3. stops accepting new Requests
+//       4. Closes all active connections, removing their interfaces and tunnels from the dataplane, and then removes
+//          the memif sockets left in s.memifSocketDir, unless s.keepConnections is set, leaving them for the next
+//          forwarder instance to restore
+//       5. gracefully stops the grpc.Server, stopping it forcibly if the grace period runs out
+//       ctx must not be done yet, as it is used for all of the above.
+func (s *shutdownSequence) run(ctx context.Context) {
+	starttime := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, s.gracePeriod)
+	defer cancel()
+	logEntry := log.Entry(ctx).WithField("shutdown", "")
+	logEntry.Infof("shutting down with a grace period of %s", s.gracePeriod)
+
+	if err := s.registrar.Unregister(ctx); err != nil {
+		logEntry.Warnf("%+v", err)
+	}
+	logEntry.Infof("unregistered from the registry (time since start: %s)", time.Since(starttime))
+
+	s.health.Shutdown()
+	s.gateServer.Shut()
+	logEntry.Infof("stopped accepting new Requests (time since start: %s)", time.Since(starttime))
+
+	if s.keepConnections {
+		logEntry.Infof("leaving %d active connections for the next forwarder instance", s.tracker.Len())
+	} else {
+		s.closeConnections(ctx)
+		logEntry.Infof("closed active connections (time since start: %s)", time.Since(starttime))
+		s.removeMemifSockets(ctx)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logEntry.Warnf("grace period elapsed, stopping grpc server forcibly")
+		s.server.Stop()
+	}
+	logEntry.Infof("shutdown completed in %s", time.Since(starttime))
+}
+
+func (s *shutdownSequence) closeConnections(ctx context.Context) {
+	conns := s.tracker.Connections()
+	log.Entry(ctx).WithField("shutdown", "").Infof("closing %d active connections", len(conns))
+	var wg sync.WaitGroup
+	for _, conn := range conns {
+		wg.Add(1)
+		go func(conn *networkservice.Connection) {
+			defer wg.Done()
+			if _, err := s.endpoint.Close(ctx, conn); err != nil {
+				log.Entry(ctx).WithField("shutdown", "").Warnf("failed to close connection %s: %+v", conn.GetId(), err)
+			}
+		}(conn)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Entry(ctx).WithField("shutdown", "").Warnf("grace period elapsed with %d connections still open", s.tracker.Len())
+	}
+}
+
+// removeMemifSockets - removes the memif sockets in s.memifSocketDir, and then s.memifSocketDir itself if the forwarder
+// created it and nothing else is left in it
+func (s *shutdownSequence) removeMemifSockets(ctx context.Context) {
+	logEntry := log.Entry(ctx).WithField("shutdown", "")
+	sockets, err := filepath.Glob(filepath.Join(s.memifSocketDir, "*"+ifnames.MemifSocketSuffix))
+	if err != nil {
+		logEntry.Warnf("failed to list memif sockets: %+v", err)
+		return
+	}
+	for _, socket := range sockets {
+		if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+			logEntry.Warnf("failed to remove memif socket: %+v", err)
+		}
+	}
+	if !s.createdDir {
+		return
+	}
+	var errno syscall.Errno
+	if err := os.Remove(s.memifSocketDir); err != nil && !os.IsNotExist(err) && !(errors.As(err, &errno) && errno == syscall.ENOTEMPTY) {
+		logEntry.Warnf("failed to remove memif socket dir: %+v", err)
+	}
+}
@@ -20,23 +20,51 @@ package main_test
 
 import (
 	"context"
+	"time"
 
+	"github.com/networkservicemesh/sdk/pkg/registry/memory"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
+// registryTimeout - longer than the maximum backoff of the forwarder's registration retries
+const registryTimeout = time.Minute
+
 func (f *ForwarderTestSuite) TestHealthCheck() {
 	ctx, cancel := context.WithTimeout(f.ctx, contextTimeout)
 	defer cancel()
 
 	healthClient := grpc_health_v1.NewHealthClient(f.sutCC)
-	healthResponse, err := healthClient.Check(ctx,
-		&grpc_health_v1.HealthCheckRequest{
-			Service: "networkservice.NetworkService",
-		},
-		grpc.WaitForReady(true),
-	)
-	f.NoError(err)
-	f.Require().NotNil(healthResponse)
-	f.Equal(grpc_health_v1.HealthCheckResponse_SERVING, healthResponse.Status)
+	for _, service := range []string{"networkservice.NetworkService", "vppagent", "dataplane"} {
+		healthResponse, err := healthClient.Check(ctx,
+			&grpc_health_v1.HealthCheckRequest{
+				Service: service,
+			},
+			grpc.WaitForReady(true),
+		)
+		f.NoError(err)
+		f.Require().NotNil(healthResponse)
+		f.Equal(grpc_health_v1.HealthCheckResponse_SERVING, healthResponse.Status, service)
+	}
+
+	// The registry of SetupSuite is gone, so serve one for the duration of the test: the forwarder registers with it
+	// again on its next attempt, which may be as far as the maximum backoff of its retries away, and its
+	// registration and overall statuses follow
+	registryCtx, registryCancel := context.WithTimeout(f.ctx, registryTimeout)
+	defer func(serverErrCh <-chan error) {
+		registryCancel()
+		f.Require().NoError(<-serverErrCh)
+	}(f.serveRegistry(registryCtx, memory.NewNetworkServiceEndpointRegistryServer()))
+	for _, service := range []string{"registry.NetworkServiceEndpointRegistry", ""} {
+		service := service
+		f.Eventually(func() bool {
+			healthResponse, err := healthClient.Check(registryCtx,
+				&grpc_health_v1.HealthCheckRequest{
+					Service: service,
+				},
+				grpc.WaitForReady(true),
+			)
+			return err == nil && healthResponse.Status == grpc_health_v1.HealthCheckResponse_SERVING
+		}, registryTimeout, 100*time.Millisecond, service)
+	}
 }
@@ -32,11 +32,7 @@ import (
 	"github.com/kelseyhightower/envconfig"
 	"github.com/networkservicemesh/api/pkg/api/registry"
 	vppagenttool "github.com/networkservicemesh/sdk-vppagent/pkg/tools/vppagent"
-	"github.com/networkservicemesh/sdk/pkg/registry/common/expire"
-	registryrecvfd "github.com/networkservicemesh/sdk/pkg/registry/common/recvfd"
-	"github.com/networkservicemesh/sdk/pkg/registry/common/setid"
 	"github.com/networkservicemesh/sdk/pkg/registry/core/adapters"
-	registrychain "github.com/networkservicemesh/sdk/pkg/registry/core/chain"
 	"github.com/networkservicemesh/sdk/pkg/registry/memory"
 	"github.com/networkservicemesh/sdk/pkg/tools/log"
 	"github.com/networkservicemesh/sdk/pkg/tools/spire"
@@ -149,27 +145,16 @@ func (f *ForwarderTestSuite) SetupSuite() {
 	log.Entry(f.ctx).Infof("Creating registryServer and registryClient (time since start: %s)", time.Since(starttime))
 	// ********************************************************************************
 	memrg := memory.NewNetworkServiceEndpointRegistryServer()
-	registryServer := registrychain.NewNetworkServiceEndpointRegistryServer(
-		setid.NewNetworkServiceEndpointRegistryServer(),
-		expire.NewNetworkServiceEndpointRegistryServer(),
-		registryrecvfd.NewNetworkServiceEndpointRegistryServer(),
-		memrg,
-	)
 
 	// ********************************************************************************
 	log.Entry(f.ctx).Infof("Get the regEndpoint from SUT (time since start: %s)", time.Since(starttime))
 	// ********************************************************************************
-	serverCreds := credentials.NewTLS(tlsconfig.MTLSServerConfig(f.x509source, f.x509bundle, tlsconfig.AuthorizeAny()))
-	serverCreds = grpcfd.TransportCredentials(serverCreds)
-	server := grpc.NewServer(grpc.Creds(serverCreds))
-
-	registry.RegisterNetworkServiceEndpointRegistryServer(server, registryServer)
 	ctx, cancel := context.WithCancel(f.ctx)
 	defer func(cancel context.CancelFunc, serverErrCh <-chan error) {
 		cancel()
 		err = <-serverErrCh
 		f.Require().NoError(err)
-	}(cancel, f.ListenAndServe(ctx, server))
+	}(cancel, f.serveRegistry(ctx, memrg))
 
 	recv, err := adapters.NetworkServiceEndpointServerToClient(memrg).Find(ctx, &registry.NetworkServiceEndpointQuery{
 		NetworkServiceEndpoint: &registry.NetworkServiceEndpoint{
@@ -29,18 +29,26 @@ import (
 	"time"
 
 	"github.com/edwarnicke/exechelper"
+	"github.com/edwarnicke/grpcfd"
 	"github.com/networkservicemesh/api/pkg/api/networkservice"
+	"github.com/networkservicemesh/api/pkg/api/registry"
 	"github.com/networkservicemesh/sdk/pkg/networkservice/chains/client"
 	"github.com/networkservicemesh/sdk/pkg/networkservice/chains/endpoint"
 	"github.com/networkservicemesh/sdk/pkg/networkservice/common/authorize"
 	"github.com/networkservicemesh/sdk/pkg/networkservice/common/mechanisms"
 	"github.com/networkservicemesh/sdk/pkg/networkservice/common/mechanisms/sendfd"
 	"github.com/networkservicemesh/sdk/pkg/networkservice/ipam/point2pointipam"
+	"github.com/networkservicemesh/sdk/pkg/registry/common/expire"
+	registryrecvfd "github.com/networkservicemesh/sdk/pkg/registry/common/recvfd"
+	"github.com/networkservicemesh/sdk/pkg/registry/common/setid"
+	registrychain "github.com/networkservicemesh/sdk/pkg/registry/core/chain"
 	"github.com/networkservicemesh/sdk/pkg/tools/grpcutils"
 	"github.com/networkservicemesh/sdk/pkg/tools/spiffejwt"
 	"github.com/pkg/errors"
+	"github.com/spiffe/go-spiffe/v2/spiffetls/tlsconfig"
 	"github.com/vishvananda/netns"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	"github.com/networkservicemesh/sdk-vppagent/pkg/networkservice/vppagent"
 
@@ -98,7 +106,7 @@ func (f *ForwarderTestSuite) server(ctx context.Context, nsName string, mechanis
 }
 
 func (f *ForwarderTestSuite) ListenAndServe(ctx context.Context, server *grpc.Server) <-chan error {
-	errCh := grpcutils.ListenAndServe(ctx, &f.config.ConnectTo, server)
+	errCh := grpcutils.ListenAndServe(ctx, &f.config.ConnectTo[0], server)
 	select {
 	case err, ok := <-errCh:
 		f.Require().True(ok)
@@ -117,6 +125,21 @@ func (f *ForwarderTestSuite) ListenAndServe(ctx context.Context, server *grpc.Se
 	return returnErrCh
 }
 
+// serveRegistry - serves the registry the forwarder registers with, backed by memrg, until ctx is done
+func (f *ForwarderTestSuite) serveRegistry(ctx context.Context, memrg registry.NetworkServiceEndpointRegistryServer) <-chan error {
+	registryServer := registrychain.NewNetworkServiceEndpointRegistryServer(
+		setid.NewNetworkServiceEndpointRegistryServer(),
+		expire.NewNetworkServiceEndpointRegistryServer(),
+		registryrecvfd.NewNetworkServiceEndpointRegistryServer(),
+		memrg,
+	)
+	serverCreds := credentials.NewTLS(tlsconfig.MTLSServerConfig(f.x509source, f.x509bundle, tlsconfig.AuthorizeAny()))
+	serverCreds = grpcfd.TransportCredentials(serverCreds)
+	server := grpc.NewServer(grpc.Creds(serverCreds))
+	registry.RegisterNetworkServiceEndpointRegistryServer(server, registryServer)
+	return f.ListenAndServe(ctx, server)
+}
+
 func (f *ForwarderTestSuite) inNamedNS(nsName string, run func(nsName string)) {
 	if nsName == "" {
 		run(nsName)